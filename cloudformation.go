@@ -0,0 +1,416 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Instances in a Multi-AZ DB cluster: one writer and two readable standbys.
+const multiAZDBClusterInstances = 3
+
+type cloudFormationTemplate struct {
+	Parameters map[string]struct {
+		Default interface{} `json:"Default"`
+	} `json:"Parameters"`
+	Mappings   map[string]map[string]map[string]interface{} `json:"Mappings"`
+	Conditions map[string]interface{}                       `json:"Conditions"`
+	Resources  map[string]cloudFormationResource            `json:"Resources"`
+}
+
+type cloudFormationResource struct {
+	Type       string                 `json:"Type"`
+	Condition  string                 `json:"Condition"`
+	Properties map[string]interface{} `json:"Properties"`
+}
+
+// GetCloudFormationInstances reads the RDS instances declared in a JSON or YAML
+// CloudFormation template. Parameters are resolved from the given overrides or
+// their defaults, and resources whose condition evaluates to false are skipped.
+func GetCloudFormationInstances(path string, region string, parameters map[string]string) ([]InstanceInfo, error) {
+	body, err := os.ReadFile(path)
+	if err != nil {
+		errorLog.Printf("Error reading CloudFormation template: %v", err)
+		return nil, err
+	}
+
+	template, err := parseCloudFormationTemplate(body)
+	if err != nil {
+		errorLog.Printf("Error parsing CloudFormation template: %v", err)
+		return nil, err
+	}
+
+	resolver := newCloudFormationResolver(template, region, parameters)
+
+	// Keep the inventory in the same order from one run to the next
+	names := make([]string, 0, len(template.Resources))
+	for name := range template.Resources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var instances []InstanceInfo
+	for _, name := range names {
+		resource := template.Resources[name]
+		if resource.Type != "AWS::RDS::DBInstance" && resource.Type != "AWS::RDS::DBCluster" {
+			continue
+		}
+
+		if resource.Condition != "" {
+			created, ok := resolver.condition(resource.Condition)
+			if !ok {
+				debugLog.Printf("Couldn't evaluate condition %s of %s, assuming it is created", resource.Condition, name)
+			} else if !created {
+				debugLog.Printf("Skipping %s, condition %s is false", name, resource.Condition)
+				continue
+			}
+		}
+
+		classProperty := "DBInstanceClass"
+		if resource.Type == "AWS::RDS::DBCluster" {
+			// Aurora clusters declare their instances as separate DBInstance
+			// resources, only Multi-AZ DB clusters set an instance class here.
+			if _, ok := resource.Properties["DBClusterInstanceClass"]; !ok {
+				continue
+			}
+			classProperty = "DBClusterInstanceClass"
+		}
+
+		instanceClass, ok := resolver.resolveString(resource.Properties[classProperty])
+		if !ok {
+			debugLog.Printf("Skipping %s, couldn't resolve its %s", name, classProperty)
+			continue
+		}
+		engine, _ := resolver.resolveString(resource.Properties["Engine"])
+		if engine == "" {
+			engine = cloudFormationClusterEngine(resolver, template, resource)
+		}
+		licenseModel, _ := resolver.resolveString(resource.Properties["LicenseModel"])
+		multiAZ, _ := resolver.resolveString(resource.Properties["MultiAZ"])
+		engineVersion, _ := resolver.resolveString(resource.Properties["EngineVersion"])
+		// Aurora replicas are separate DBInstance resources, not Multi-AZ standbys
+		isMultiAZ := strings.EqualFold(multiAZ, "true") && !isAuroraEngine(determineServiceFromDBEngine(&engine))
+
+		count := instanceCountForDeployment(1, isMultiAZ)
+		if resource.Type == "AWS::RDS::DBCluster" {
+			isMultiAZ, count = true, multiAZDBClusterInstances
+		}
+
+		instances = append(instances, InstanceInfo{
+			InstanceType:      instanceClass,
+			NumberOfInstances: count,
+			Engine:            rdsEngine(engine, licenseModel),
+			MultiAZ:           isMultiAZ,
+			Tags:              resolver.resolveTags(resource.Properties["Tags"]),
+			Identifier:        cloudFormationIdentifier(resolver, name, resource),
+			EngineVersion:     engineVersion,
+		})
+	}
+
+	debugLog.Printf("Found CloudFormation instances: %v", instances)
+	return instances, nil
+}
+
+func parseCloudFormationTemplate(body []byte) (*cloudFormationTemplate, error) {
+	var template cloudFormationTemplate
+	if json.Unmarshal(body, &template) == nil {
+		return &template, nil
+	}
+
+	// Not JSON, convert the YAML document (including the short form intrinsic
+	// functions such as !Ref) to its JSON equivalent.
+	var document yaml.Node
+	if err := yaml.Unmarshal(body, &document); err != nil {
+		return nil, err
+	}
+	value, err := cloudFormationYAMLValue(&document)
+	if err != nil {
+		return nil, err
+	}
+	converted, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(converted, &template); err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+func cloudFormationYAMLValue(node *yaml.Node) (interface{}, error) {
+	if strings.HasPrefix(node.Tag, "!") && !strings.HasPrefix(node.Tag, "!!") {
+		function := strings.TrimPrefix(node.Tag, "!")
+		untagged := *node
+		untagged.Tag = ""
+		if node.Kind == yaml.ScalarNode {
+			untagged.Tag = "!!str"
+		}
+		value, err := cloudFormationYAMLValue(&untagged)
+		if err != nil {
+			return nil, err
+		}
+
+		switch function {
+		case "Ref", "Condition":
+			return map[string]interface{}{function: value}, nil
+		case "GetAtt":
+			if s, ok := value.(string); ok {
+				resource, attribute, _ := strings.Cut(s, ".")
+				value = []interface{}{resource, attribute}
+			}
+		}
+		return map[string]interface{}{"Fn::" + function: value}, nil
+	}
+
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return nil, nil
+		}
+		return cloudFormationYAMLValue(node.Content[0])
+	case yaml.AliasNode:
+		return cloudFormationYAMLValue(node.Alias)
+	case yaml.MappingNode:
+		mapping := make(map[string]interface{}, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			value, err := cloudFormationYAMLValue(node.Content[i+1])
+			if err != nil {
+				return nil, err
+			}
+			mapping[node.Content[i].Value] = value
+		}
+		return mapping, nil
+	case yaml.SequenceNode:
+		sequence := make([]interface{}, 0, len(node.Content))
+		for _, item := range node.Content {
+			value, err := cloudFormationYAMLValue(item)
+			if err != nil {
+				return nil, err
+			}
+			sequence = append(sequence, value)
+		}
+		return sequence, nil
+	default:
+		var value interface{}
+		err := node.Decode(&value)
+		return value, err
+	}
+}
+
+// cloudFormationResolver evaluates the subset of intrinsic functions that can be
+// resolved statically: Ref to parameters, Fn::If, Fn::FindInMap, Fn::Sub and the
+// condition functions.
+type cloudFormationResolver struct {
+	template   *cloudFormationTemplate
+	parameters map[string]interface{}
+	evaluating map[string]bool
+}
+
+func newCloudFormationResolver(template *cloudFormationTemplate, region string, overrides map[string]string) *cloudFormationResolver {
+	parameters := map[string]interface{}{"AWS::Region": region}
+	for name, parameter := range template.Parameters {
+		if parameter.Default != nil {
+			parameters[name] = parameter.Default
+		}
+	}
+	for name, value := range overrides {
+		parameters[name] = value
+	}
+	return &cloudFormationResolver{
+		template:   template,
+		parameters: parameters,
+		evaluating: make(map[string]bool),
+	}
+}
+
+var cloudFormationSubVariable = regexp.MustCompile(`\$\{([^}!]+)\}`)
+
+func (r *cloudFormationResolver) resolve(value interface{}) (interface{}, bool) {
+	function, ok := value.(map[string]interface{})
+	if !ok {
+		return value, value != nil
+	}
+	if len(function) != 1 {
+		return nil, false
+	}
+
+	for name, args := range function {
+		list, _ := args.([]interface{})
+		switch name {
+		case "Ref":
+			ref, _ := args.(string)
+			parameter, ok := r.parameters[ref]
+			return parameter, ok
+		case "Fn::If":
+			if len(list) != 3 {
+				return nil, false
+			}
+			conditionName, _ := list[0].(string)
+			result, ok := r.condition(conditionName)
+			if !ok {
+				return nil, false
+			}
+			if result {
+				return r.resolve(list[1])
+			}
+			return r.resolve(list[2])
+		case "Fn::FindInMap":
+			if len(list) != 3 {
+				return nil, false
+			}
+			var keys []string
+			for _, arg := range list {
+				key, ok := r.resolveString(arg)
+				if !ok {
+					return nil, false
+				}
+				keys = append(keys, key)
+			}
+			mapped, ok := r.template.Mappings[keys[0]][keys[1]][keys[2]]
+			return mapped, ok
+		case "Fn::Sub":
+			format, ok := args.(string)
+			if !ok {
+				return nil, false
+			}
+			resolved := true
+			result := cloudFormationSubVariable.ReplaceAllStringFunc(format, func(variable string) string {
+				name := cloudFormationSubVariable.FindStringSubmatch(variable)[1]
+				parameter, ok := r.resolveString(map[string]interface{}{"Ref": name})
+				if !ok {
+					resolved = false
+				}
+				return parameter
+			})
+			return result, resolved
+		}
+	}
+	return nil, false
+}
+
+func (r *cloudFormationResolver) resolveString(value interface{}) (string, bool) {
+	resolved, ok := r.resolve(value)
+	if !ok {
+		return "", false
+	}
+	switch v := resolved.(type) {
+	case string:
+		return v, true
+	case bool, int, float64:
+		return fmt.Sprint(v), true
+	}
+	return "", false
+}
+
+// cloudFormationClusterEngine returns the engine of the DB cluster an instance
+// references, the Aurora instances of a template often leaving it out.
+func cloudFormationClusterEngine(resolver *cloudFormationResolver, template *cloudFormationTemplate, resource cloudFormationResource) string {
+	ref, ok := resource.Properties["DBClusterIdentifier"].(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	name, _ := ref["Ref"].(string)
+	cluster, ok := template.Resources[name]
+	if !ok || cluster.Type != "AWS::RDS::DBCluster" {
+		return ""
+	}
+	engine, _ := resolver.resolveString(cluster.Properties["Engine"])
+	return engine
+}
+
+// cloudFormationIdentifier returns the DB identifier set in the template, or the
+// logical ID of the resource when it is left for CloudFormation to generate.
+func cloudFormationIdentifier(resolver *cloudFormationResolver, name string, resource cloudFormationResource) string {
+	property := "DBInstanceIdentifier"
+	if resource.Type == "AWS::RDS::DBCluster" {
+		property = "DBClusterIdentifier"
+	}
+	if identifier, ok := resolver.resolveString(resource.Properties[property]); ok && identifier != "" {
+		return identifier
+	}
+	return name
+}
+
+// resolveTags converts a CloudFormation list of Key/Value tags, skipping the
+// ones that can't be resolved.
+func (r *cloudFormationResolver) resolveTags(value interface{}) map[string]string {
+	tags := make(map[string]string)
+	list, _ := value.([]interface{})
+	for _, item := range list {
+		tag, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		key, keyOK := r.resolveString(tag["Key"])
+		val, valueOK := r.resolveString(tag["Value"])
+		if keyOK && valueOK {
+			tags[key] = val
+		}
+	}
+	return tags
+}
+
+// condition evaluates a named condition from the template's Conditions section.
+func (r *cloudFormationResolver) condition(name string) (bool, bool) {
+	expression, ok := r.template.Conditions[name]
+	if !ok || r.evaluating[name] {
+		return false, false
+	}
+	r.evaluating[name] = true
+	defer delete(r.evaluating, name)
+
+	return r.evaluateCondition(expression)
+}
+
+func (r *cloudFormationResolver) evaluateCondition(expression interface{}) (bool, bool) {
+	function, ok := expression.(map[string]interface{})
+	if !ok || len(function) != 1 {
+		return false, false
+	}
+
+	for name, args := range function {
+		list, _ := args.([]interface{})
+		switch name {
+		case "Condition":
+			conditionName, _ := args.(string)
+			return r.condition(conditionName)
+		case "Fn::Equals":
+			if len(list) != 2 {
+				return false, false
+			}
+			left, ok := r.resolveString(list[0])
+			if !ok {
+				return false, false
+			}
+			right, ok := r.resolveString(list[1])
+			if !ok {
+				return false, false
+			}
+			return left == right, true
+		case "Fn::Not":
+			if len(list) != 1 {
+				return false, false
+			}
+			result, ok := r.evaluateCondition(list[0])
+			return !result, ok
+		case "Fn::And", "Fn::Or":
+			and := name == "Fn::And"
+			for _, item := range list {
+				result, ok := r.evaluateCondition(item)
+				if !ok {
+					return false, false
+				}
+				if result != and {
+					return result, true
+				}
+			}
+			return and, true
+		}
+	}
+	return false, false
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// cloudFormationInstance is the part of an inventory entry read from a template.
+type cloudFormationInstance struct {
+	Identifier   string
+	InstanceType string
+	Engine       string
+	Count        int
+	MultiAZ      bool
+}
+
+// getTemplateInstances reads the inventory of a template written to a temporary file.
+func getTemplateInstances(t *testing.T, template string, parameters map[string]string) []cloudFormationInstance {
+	path := filepath.Join(t.TempDir(), "template")
+	if err := os.WriteFile(path, []byte(template), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	instances, err := GetCloudFormationInstances(path, "eu-west-1", parameters)
+	if err != nil {
+		t.Fatalf("reading the template failed: %v", err)
+	}
+
+	var got []cloudFormationInstance
+	for _, instance := range instances {
+		got = append(got, cloudFormationInstance{
+			Identifier:   instance.Identifier,
+			InstanceType: instance.InstanceType,
+			Engine:       instance.Engine,
+			Count:        instance.NumberOfInstances,
+			MultiAZ:      instance.MultiAZ,
+		})
+	}
+	return got
+}
+
+func checkTemplateInstances(t *testing.T, got, want []cloudFormationInstance) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d instances %+v, want %d %+v", len(got), got, len(want), want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("instance %d: got %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+// Conditions shared by the condition tests, Prod being true with the default parameters.
+const cloudFormationConditions = `
+Parameters:
+  Env:
+    Default: prod
+  Size:
+    Default: large
+Conditions:
+  Prod:
+    Fn::Equals: [Ref: Env, prod]
+  NotProd:
+    Fn::Not: [Condition: Prod]
+  ProdLarge:
+    Fn::And: [Condition: Prod, Fn::Equals: [Ref: Size, large]]
+  DevOrSmall:
+    Fn::Or: [Condition: NotProd, Fn::Equals: [Ref: Size, small]]
+`
+
+func TestCloudFormationIntrinsics(t *testing.T) {
+	tests := []struct {
+		name       string
+		template   string
+		parameters map[string]string
+		want       []cloudFormationInstance
+	}{
+		{
+			name: "Ref to a parameter default",
+			template: `{
+  "Parameters": {"DBClass": {"Default": "db.m5.large"}},
+  "Resources": {"DB": {"Type": "AWS::RDS::DBInstance", "Properties": {"DBInstanceClass": {"Ref": "DBClass"}, "Engine": "postgres"}}}
+}`,
+			want: []cloudFormationInstance{{"DB", "db.m5.large", "PostgreSQL", 1, false}},
+		},
+		{
+			name: "Ref to an overridden parameter",
+			template: `{
+  "Parameters": {"DBClass": {"Default": "db.m5.large"}},
+  "Resources": {"DB": {"Type": "AWS::RDS::DBInstance", "Properties": {"DBInstanceClass": {"Ref": "DBClass"}, "Engine": "postgres"}}}
+}`,
+			parameters: map[string]string{"DBClass": "db.r6g.xlarge"},
+			want:       []cloudFormationInstance{{"DB", "db.r6g.xlarge", "PostgreSQL", 1, false}},
+		},
+		{
+			name: "Ref to an unknown parameter skips the resource",
+			template: `{
+  "Resources": {"DB": {"Type": "AWS::RDS::DBInstance", "Properties": {"DBInstanceClass": {"Ref": "Missing"}, "Engine": "postgres"}}}
+}`,
+		},
+		{
+			name: "Fn::If",
+			template: `{
+  "Parameters": {"Env": {"Default": "dev"}},
+  "Conditions": {"Prod": {"Fn::Equals": [{"Ref": "Env"}, "prod"]}},
+  "Resources": {"DB": {"Type": "AWS::RDS::DBInstance", "Properties": {
+    "DBInstanceClass": {"Fn::If": ["Prod", "db.r5.2xlarge", "db.t3.medium"]},
+    "Engine": "mysql",
+    "MultiAZ": {"Fn::If": ["Prod", true, false]}}}}
+}`,
+			want: []cloudFormationInstance{{"DB", "db.t3.medium", "MySQL", 1, false}},
+		},
+		{
+			name: "Fn::If with the condition overridden",
+			template: `{
+  "Parameters": {"Env": {"Default": "dev"}},
+  "Conditions": {"Prod": {"Fn::Equals": [{"Ref": "Env"}, "prod"]}},
+  "Resources": {"DB": {"Type": "AWS::RDS::DBInstance", "Properties": {
+    "DBInstanceClass": {"Fn::If": ["Prod", "db.r5.2xlarge", "db.t3.medium"]},
+    "Engine": "mysql",
+    "MultiAZ": {"Fn::If": ["Prod", true, false]}}}}
+}`,
+			parameters: map[string]string{"Env": "prod"},
+			want:       []cloudFormationInstance{{"DB", "db.r5.2xlarge", "MySQL", 2, true}},
+		},
+		{
+			name: "Fn::FindInMap keyed by the region",
+			template: `{
+  "Mappings": {"Classes": {"eu-west-1": {"Primary": "db.m6g.large"}, "us-east-1": {"Primary": "db.m5.large"}}},
+  "Resources": {"DB": {"Type": "AWS::RDS::DBInstance", "Properties": {
+    "DBInstanceClass": {"Fn::FindInMap": ["Classes", {"Ref": "AWS::Region"}, "Primary"]}, "Engine": "postgres"}}}
+}`,
+			want: []cloudFormationInstance{{"DB", "db.m6g.large", "PostgreSQL", 1, false}},
+		},
+		{
+			name: "Fn::Sub of the parameters and region",
+			template: `{
+  "Parameters": {"Env": {"Default": "prod"}},
+  "Resources": {"DB": {"Type": "AWS::RDS::DBInstance", "Properties": {
+    "DBInstanceIdentifier": {"Fn::Sub": "${Env}-orders-${AWS::Region}"}, "DBInstanceClass": "db.m5.large", "Engine": "postgres"}}}
+}`,
+			want: []cloudFormationInstance{{"prod-orders-eu-west-1", "db.m5.large", "PostgreSQL", 1, false}},
+		},
+		{
+			name: "Fn::Sub of an unknown variable keeps the logical ID",
+			template: `{
+  "Resources": {"DB": {"Type": "AWS::RDS::DBInstance", "Properties": {
+    "DBInstanceIdentifier": {"Fn::Sub": "${Missing}-orders"}, "DBInstanceClass": "db.m5.large", "Engine": "postgres"}}}
+}`,
+			want: []cloudFormationInstance{{"DB", "db.m5.large", "PostgreSQL", 1, false}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checkTemplateInstances(t, getTemplateInstances(t, test.template, test.parameters), test.want)
+		})
+	}
+}
+
+func TestCloudFormationConditions(t *testing.T) {
+	tests := []struct {
+		condition  string
+		parameters map[string]string
+		created    bool
+	}{
+		{"Prod", nil, true},
+		{"Prod", map[string]string{"Env": "dev"}, false},
+		{"NotProd", nil, false},
+		{"NotProd", map[string]string{"Env": "dev"}, true},
+		{"ProdLarge", nil, true},
+		{"ProdLarge", map[string]string{"Size": "small"}, false},
+		{"DevOrSmall", nil, false},
+		{"DevOrSmall", map[string]string{"Size": "small"}, true},
+		{"Unknown", nil, true}, // Conditions that can't be evaluated are assumed true
+	}
+	for _, test := range tests {
+		t.Run(test.condition, func(t *testing.T) {
+			template := cloudFormationConditions + `
+Resources:
+  DB:
+    Type: AWS::RDS::DBInstance
+    Condition: ` + test.condition + `
+    Properties:
+      DBInstanceClass: db.m5.large
+      Engine: postgres
+`
+			var want []cloudFormationInstance
+			if test.created {
+				want = []cloudFormationInstance{{"DB", "db.m5.large", "PostgreSQL", 1, false}}
+			}
+			checkTemplateInstances(t, getTemplateInstances(t, template, test.parameters), want)
+		})
+	}
+}
+
+func TestCloudFormationYAMLShortTags(t *testing.T) {
+	tests := []struct {
+		name  string
+		class string
+		want  string
+	}{
+		{"Ref", "!Ref DBClass", "db.m5.large"},
+		{"If", "!If [Prod, db.r5.xlarge, db.t3.medium]", "db.r5.xlarge"},
+		{"FindInMap", "!FindInMap [Classes, !Ref 'AWS::Region', Primary]", "db.m6g.large"},
+		{"Sub", "!Sub 'db.${Family}.large'", "db.r6g.large"},
+		{"Equals", "!If [IsProd, db.r5.xlarge, db.t3.medium]", "db.r5.xlarge"},
+		{"Not", "!If [NotProd, db.r5.xlarge, db.t3.medium]", "db.t3.medium"},
+		{"And", "!If [ProdAndGraviton, db.r6g.xlarge, db.t3.medium]", "db.r6g.xlarge"},
+		{"Or", "!If [DevOrX86, db.r5.xlarge, db.t3.medium]", "db.t3.medium"},
+		{"Condition", "!If [Prod, db.r5.xlarge, db.t3.medium]", "db.r5.xlarge"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			template := `
+Parameters:
+  DBClass:
+    Default: db.m5.large
+  Env:
+    Default: prod
+  Family:
+    Default: r6g
+Mappings:
+  Classes:
+    eu-west-1:
+      Primary: db.m6g.large
+Conditions:
+  IsProd: !Equals [!Ref Env, prod]
+  Prod: !Condition IsProd
+  NotProd: !Not [!Condition IsProd]
+  ProdAndGraviton: !And [!Condition IsProd, !Equals [!Ref Family, r6g]]
+  DevOrX86: !Or [!Condition NotProd, !Equals [!Ref Family, r5]]
+Resources:
+  DB:
+    Type: AWS::RDS::DBInstance
+    Properties:
+      DBInstanceClass: ` + test.class + `
+      Engine: postgres
+`
+			want := []cloudFormationInstance{{"DB", test.want, "PostgreSQL", 1, false}}
+			checkTemplateInstances(t, getTemplateInstances(t, template, nil), want)
+		})
+	}
+}
+
+func TestCloudFormationDeployments(t *testing.T) {
+	template := `
+Resources:
+  OrdersDB:
+    Type: AWS::RDS::DBInstance
+    Properties:
+      DBInstanceClass: db.m5.large
+      Engine: postgres
+      MultiAZ: true
+  AuroraCluster:
+    Type: AWS::RDS::DBCluster
+    Properties:
+      Engine: aurora-postgresql
+  AuroraWriter:
+    Type: AWS::RDS::DBInstance
+    Properties:
+      DBClusterIdentifier: !Ref AuroraCluster
+      DBInstanceClass: db.r6g.large
+      MultiAZ: true
+  MultiAZCluster:
+    Type: AWS::RDS::DBCluster
+    Properties:
+      DBClusterIdentifier: billing
+      DBClusterInstanceClass: db.m6gd.large
+      Engine: mysql
+  Bucket:
+    Type: AWS::S3::Bucket
+`
+	// Sorted by logical ID; the Aurora cluster only declares its instances, while
+	// the Multi-AZ DB cluster runs a writer and two readable standbys
+	want := []cloudFormationInstance{
+		{"AuroraWriter", "db.r6g.large", "Aurora PostgreSQL", 1, false},
+		{"billing", "db.m6gd.large", "MySQL", multiAZDBClusterInstances, true},
+		{"OrdersDB", "db.m5.large", "PostgreSQL", 2, true},
+	}
+	for i := 0; i < 5; i++ {
+		checkTemplateInstances(t, getTemplateInstances(t, template, nil), want)
+	}
+}
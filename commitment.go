@@ -0,0 +1,270 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// Database services covered by the commitment based discounts, such as the
+// Database Savings Plans, the RDS ones including DocumentDB and Neptune.
+var commitmentServices = []string{
+	ServiceRDS,
+	ServiceElastiCache,
+	ServiceMemoryDB,
+	ServiceOpenSearch,
+	ServiceRedshift,
+}
+
+// isCommitmentService reports whether the commitment based discounts apply to the service.
+func isCommitmentService(service string) bool {
+	for _, s := range commitmentServices {
+		if s == service {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseCommitmentDiscounts parses the comma separated Years=Percent discounts of
+// the modeled commitment terms, e.g. 1=20,3=35, keyed by term.
+func ParseCommitmentDiscounts(value string) (map[string]float64, error) {
+	pairs, err := ParseKeyValuePairs(value)
+	if err != nil {
+		return nil, err
+	}
+
+	discounts := make(map[string]float64, len(pairs))
+	for years, percent := range pairs {
+		if years != "1" && years != "3" {
+			return nil, fmt.Errorf("invalid term %q, expected 1 or 3", years)
+		}
+		discount, err := strconv.ParseFloat(percent, 64)
+		if err != nil || discount <= 0 || discount >= 100 {
+			return nil, fmt.Errorf("invalid discount %q for the %s year term, expected a percent between 0 and 100", percent, years)
+		}
+		discounts[years+" Year"] = discount
+	}
+	return discounts, nil
+}
+
+// ParseCommitmentRegions parses the comma separated regions whose database spend
+// the commitment covers, which must be in the partition of the run's region as
+// the commitments don't span partitions. The run's region is always included.
+func ParseCommitmentRegions(value, region string) ([]string, error) {
+	regions := []string{region}
+	if value == "" {
+		return regions, nil
+	}
+	for _, r := range strings.Split(value, ",") {
+		r = strings.TrimSpace(r)
+		if r == "" || r == region {
+			continue
+		}
+		if regionPartition(r) != regionPartition(region) {
+			return nil, fmt.Errorf("%s isn't in the %s partition of %s", r, regionPartition(region), region)
+		}
+		regions = append(regions, r)
+	}
+	return regions, nil
+}
+
+// CommitmentSpend is the spend of the instances of a service in a region, which an
+// hourly commitment can cover.
+type CommitmentSpend struct {
+	Region    string
+	Service   string
+	Summaries map[string]SavingsSummary // By term
+	Err       error                     // Why the instances couldn't be priced
+}
+
+// commitmentSpend summarizes the spend of the priced instances of a service in a region.
+func commitmentSpend(region, service string, instances []InstanceInfo, data1Year, data3Years []PricingData) CommitmentSpend {
+	return CommitmentSpend{
+		Region:  region,
+		Service: service,
+		Summaries: map[string]SavingsSummary{
+			"1 Year": SummarizeSavings("", data1Year, instances, "1 Year"),
+			"3 Year": SummarizeSavings("", data3Years, instances, "3 Year"),
+		},
+	}
+}
+
+// CollectCommitmentSpend fetches and prices the instances of every database service
+// in each region, the spend of the run's own service and region being the given
+// one. The inventory files only describe the run's service and region, so no other
+// spend is collected with them.
+func CollectCommitmentSpend(regions []string, statusPolicy string, current CommitmentSpend) []CommitmentSpend {
+	spends := []CommitmentSpend{current}
+	if TerraformFile != "" || CloudFormationFile != "" {
+		return spends
+	}
+
+	// The inventory and pricing functions use the service and region of the run
+	service, region, statuses := Service, Region, IncludedStatuses
+	defer func() {
+		Service, Region, IncludedStatuses = service, region, statuses
+	}()
+
+	for _, r := range regions {
+		for _, s := range commitmentServices {
+			if r == current.Region && s == current.Service {
+				continue
+			}
+			Service, Region = s, r
+			spend := CommitmentSpend{Region: r, Service: s}
+
+			var err error
+			IncludedStatuses, err = ParseStatusPolicy(statusPolicy, s)
+			if err == nil {
+				err = CheckPricingAvailable(r)
+			}
+			var instances []InstanceInfo
+			if err == nil {
+				instances, _, err = FetchInstances(r)
+			}
+			if err != nil {
+				errorLog.Printf("Failed to collect the %s spend in %s: %v", serviceName(s), r, err)
+				spend.Err = err
+				spends = append(spends, spend)
+				continue
+			}
+
+			aggregated := aggregateInstances(instances)
+			data1Year, data3Years := ProcessPricingData(r, aggregated)
+			spends = append(spends, commitmentSpend(r, s, aggregated, data1Year, data3Years))
+		}
+	}
+	return spends
+}
+
+// CommitmentComparison is the cost of one way of paying for all the instances over
+// a term, and what it can be applied to.
+type CommitmentComparison struct {
+	Term           string
+	Option         string
+	HourlyCost     float64 // The hourly commitment, or the amortized hourly cost of the other options
+	CostForTerm    float64
+	Savings        float64
+	SavingsPercent float64
+	AppliesTo      string
+}
+
+// CompareCommitment recommends an hourly spend commitment covering the on-demand
+// spend of all the collected instances, across their services, engines and
+// regions, at the modeled discount of each term, and compares it with staying
+// on-demand and with the cheapest per-instance reservations.
+func CompareCommitment(spends []CommitmentSpend, discounts map[string]float64) []CommitmentComparison {
+	var comparisons []CommitmentComparison
+	for _, years := range []int{1, 3} {
+		term := fmt.Sprintf("%d Year", years)
+		discount, ok := discounts[term]
+		if !ok {
+			continue
+		}
+
+		var onDemandCost, bestReservedCost float64
+		for _, spend := range spends {
+			onDemandCost += spend.Summaries[term].OnDemandCost
+			bestReservedCost += spend.Summaries[term].BestReservedCost
+		}
+		if onDemandCost == 0 {
+			continue
+		}
+		hours := termHours(years)
+		commitment := onDemandCost / hours * (1 - discount/100)
+
+		for _, option := range []struct {
+			Name      string
+			Cost      float64
+			AppliesTo string
+		}{
+			{"On-Demand", onDemandCost, "No commitment"},
+			{"Per-Instance Reservations", bestReservedCost, "Instance family, engine and region of each reservation"},
+			{"Hourly Commitment", commitment * hours, "Any engine, instance family and region"},
+		} {
+			savings := onDemandCost - option.Cost
+			comparisons = append(comparisons, CommitmentComparison{
+				Term:           term,
+				Option:         option.Name,
+				HourlyCost:     option.Cost / hours,
+				CostForTerm:    option.Cost,
+				Savings:        savings,
+				SavingsPercent: savings / onDemandCost * 100,
+				AppliesTo:      option.AppliesTo,
+			})
+		}
+	}
+	return comparisons
+}
+
+// PrintCommitmentComparison prints the recommended hourly commitment of each term
+// next to the on-demand and per-instance reservation costs, followed by the spend
+// of each service and region it covers.
+func PrintCommitmentComparison(comparisons []CommitmentComparison, spends []CommitmentSpend) {
+	if len(comparisons) == 0 {
+		return
+	}
+
+	fmt.Println("\n## Hourly Commitment vs Per-Instance Reservations")
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader(currencyHeaders([]string{
+		"Term",
+		"Option",
+		"Hourly Cost ($)",
+		"Cost for Term ($)",
+		"Savings ($)",
+		"Savings (%)",
+		"Applies To",
+	}))
+	table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	table.SetCenterSeparator("|")
+
+	for _, comparison := range comparisons {
+		table.Append([]string{
+			comparison.Term,
+			comparison.Option,
+			fmt.Sprintf("%.4f", comparison.HourlyCost*ExchangeRate),
+			formatCost(comparison.CostForTerm),
+			formatCost(comparison.Savings),
+			fmt.Sprintf("%.2f", comparison.SavingsPercent),
+			comparison.AppliesTo,
+		})
+	}
+	table.Render()
+
+	fmt.Println("\n## Commitment Spend by Region and Service")
+	table = tablewriter.NewWriter(os.Stdout)
+	table.SetHeader(currencyHeaders([]string{
+		"Region",
+		"Service",
+		"Number of Instances",
+		"1 Year On-Demand Cost ($)",
+		"1 Year Best Reserved Cost ($)",
+		"Note",
+	}))
+	table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	table.SetCenterSeparator("|")
+
+	for _, spend := range spends {
+		summary := spend.Summaries["1 Year"]
+		note := ""
+		if spend.Err != nil {
+			note = fmt.Sprintf("Not covered: %v", spend.Err)
+		} else if summary.NumberOfInstances == 0 {
+			continue
+		}
+		table.Append([]string{
+			spend.Region,
+			serviceName(spend.Service),
+			fmt.Sprintf("%d", summary.NumberOfInstances),
+			formatCost(summary.OnDemandCost),
+			formatCost(summary.BestReservedCost),
+			note,
+		})
+	}
+	table.Render()
+}
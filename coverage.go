@@ -0,0 +1,229 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// CoveragePoint holds the expected savings of reserving a number of instances.
+type CoveragePoint struct {
+	Quantity           int
+	CoveragePercent    float64 // Share of the running instance hours covered by the reservations
+	UtilizationPercent float64 // Share of the reserved hours used by running instances
+	Savings            float64
+	SavingsPercent     float64
+}
+
+// CoverageCurve holds the expected savings of an instance type and engine for each
+// number of reservations, from none up to the peak running count.
+type CoverageCurve struct {
+	InstanceType  string
+	Engine        string
+	Term          string
+	OfferingClass string
+	PaymentOption string
+	MeanInstances float64
+	PeakInstances int
+	Optimal       int // Index of the point with the highest expected savings
+	Points        []CoveragePoint
+}
+
+// GetRunningCountHistory returns, for each instance type and engine, the number of
+// instances running during each hour of the last days, based on the hours having
+// CPUUtilization datapoints.
+func GetRunningCountHistory(region string, instances []InstanceInfo, days int) (map[forecastKey][]int, error) {
+	end := time.Now().Truncate(time.Hour)
+	start := end.AddDate(0, 0, -days)
+
+	var metrics []rdsMetricQuery
+	for _, instance := range instances {
+		metrics = append(metrics, rdsMetricQuery{Identifier: instance.Identifier, MetricName: "CPUUtilization", Stat: "Maximum"})
+	}
+
+	runningHours, err := getRDSMetricHours(region, metrics, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	hoursInWindow := int(end.Sub(start).Hours())
+	history := make(map[forecastKey][]int)
+	for _, instance := range instances {
+		key := forecastKey{instance.InstanceType, instance.Engine}
+		if _, ok := history[key]; !ok {
+			history[key] = make([]int, hoursInWindow)
+		}
+		for _, hour := range runningHours[rdsMetricQuery{Identifier: instance.Identifier, MetricName: "CPUUtilization", Stat: "Maximum"}] {
+			if i := int(hour.Sub(start).Hours()); i >= 0 && i < hoursInWindow {
+				history[key][i] += instance.NumberOfInstances
+			}
+		}
+	}
+
+	debugLog.Printf("Hourly running counts over the last %d days: %v", days, history)
+	return history, nil
+}
+
+// OptimizeCoverage computes, for both terms, the expected savings of reserving each
+// number of instances given the distribution of their hourly running counts and the
+// pricing rows of all the instances.
+func OptimizeCoverage(region string, instances []InstanceInfo, data1Year, data3Years []PricingData, days int) ([]CoverageCurve, error) {
+	history, err := GetRunningCountHistory(region, instances, days)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []forecastKey
+	for key := range history {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].InstanceType != keys[j].InstanceType {
+			return keys[i].InstanceType < keys[j].InstanceType
+		}
+		return keys[i].Engine < keys[j].Engine
+	})
+
+	var curves []CoverageCurve
+	for _, key := range keys {
+		for _, term := range []struct {
+			Name   string
+			Months int
+			Data   []PricingData
+		}{{"1 Year", 12, data1Year}, {"3 Year", 36, data3Years}} {
+			onDemand, reserved := coverageOptions(term.Data, key)
+			if onDemand == nil || reserved == nil {
+				debugLog.Printf("No %s reserved pricing for %s %s", term.Name, key.Engine, key.InstanceType)
+				continue
+			}
+
+			curve := coverageCurve(history[key], onDemand.MonthlyCostPerInstance, reserved.AmortizedMonthlyCostPerInstance, term.Months)
+			curve.InstanceType = key.InstanceType
+			curve.Engine = key.Engine
+			curve.Term = term.Name
+			curve.OfferingClass = reserved.OfferingClass
+			curve.PaymentOption = reserved.PaymentOption
+			curves = append(curves, curve)
+		}
+	}
+	return curves, nil
+}
+
+// coverageOptions returns the on-demand row of an instance type and engine, and its
+// cheapest amortized reserved option offered by AWS, which gives the highest savings
+// at any quantity.
+func coverageOptions(data []PricingData, key forecastKey) (*PricingData, *PricingData) {
+	var onDemand, reserved *PricingData
+	for i, row := range data {
+		if row.InstanceType != key.InstanceType || row.Engine != key.Engine {
+			continue
+		}
+		switch {
+		case row.Term == "On-Demand":
+			onDemand = &data[i]
+		case row.OfferingStatus == OfferingStatusNotOffered:
+			debugLog.Printf("Skipping the %s %s %s option of %s, not offered", row.Term, row.OfferingClass, row.PaymentOption, row.InstanceType)
+		case reserved == nil || row.AmortizedMonthlyCostPerInstance < reserved.AmortizedMonthlyCostPerInstance:
+			reserved = &data[i]
+		}
+	}
+	return onDemand, reserved
+}
+
+// coverageCurve computes the expected term savings of each number of reservations
+// for the given hourly running counts, the hours above the reserved quantity being
+// paid on-demand.
+func coverageCurve(hourlyCounts []int, onDemandMonthly, reservedMonthly float64, months int) CoverageCurve {
+	var curve CoverageCurve
+	if len(hourlyCounts) == 0 {
+		return curve
+	}
+
+	total := 0
+	for _, count := range hourlyCounts {
+		total += count
+		curve.PeakInstances = max(curve.PeakInstances, count)
+	}
+	hours := float64(len(hourlyCounts))
+	curve.MeanInstances = float64(total) / hours
+	onDemandCost := curve.MeanInstances * onDemandMonthly * float64(months)
+
+	for quantity := 0; quantity <= curve.PeakInstances; quantity++ {
+		covered := 0
+		for _, count := range hourlyCounts {
+			covered += min(count, quantity)
+		}
+		uncovered := float64(total-covered) / hours
+
+		point := CoveragePoint{Quantity: quantity}
+		cost := (float64(quantity)*reservedMonthly + uncovered*onDemandMonthly) * float64(months)
+		point.Savings = onDemandCost - cost
+		if total != 0 {
+			point.CoveragePercent = float64(covered) / float64(total) * 100
+		}
+		if quantity != 0 {
+			point.UtilizationPercent = float64(covered) / (hours * float64(quantity)) * 100
+		}
+		if onDemandCost != 0 {
+			point.SavingsPercent = point.Savings / onDemandCost * 100
+		}
+
+		curve.Points = append(curve.Points, point)
+		if point.Savings > curve.Points[curve.Optimal].Savings {
+			curve.Optimal = len(curve.Points) - 1
+		}
+	}
+	return curve
+}
+
+// PrintCoverageCurves prints the expected savings of each number of reservations,
+// marking the number maximizing them.
+func PrintCoverageCurves(curves []CoverageCurve, days int) {
+	fmt.Printf("\n## Reservation Coverage over the last %d days\n", days)
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader(currencyHeaders([]string{
+		"Instance Type",
+		"Engine",
+		"Term",
+		"Offering Class",
+		"Payment Option",
+		"Mean Running Instances",
+		"Reserved Instances",
+		"Coverage (%)",
+		"Reservation Utilization (%)",
+		"Expected Savings ($)",
+		"Expected Savings (%)",
+		"Optimal",
+	}))
+	table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	table.SetCenterSeparator("|")
+
+	for _, curve := range curves {
+		for i, point := range curve.Points {
+			optimal := ""
+			if i == curve.Optimal {
+				optimal = "*"
+			}
+			table.Append([]string{
+				curve.InstanceType,
+				curve.Engine,
+				curve.Term,
+				curve.OfferingClass,
+				curve.PaymentOption,
+				fmt.Sprintf("%.2f", curve.MeanInstances),
+				fmt.Sprintf("%d", point.Quantity),
+				fmt.Sprintf("%.2f", point.CoveragePercent),
+				fmt.Sprintf("%.2f", point.UtilizationPercent),
+				formatCost(point.Savings),
+				fmt.Sprintf("%.2f", point.SavingsPercent),
+				optimal,
+			})
+		}
+	}
+
+	table.Render()
+	fmt.Printf("\nNote: the running counts only include the instances existing today. The instances deleted during the last %d days are missing from the history, so the coverage and utilization of fleets that replace their instances may be overstated.\n", days)
+}
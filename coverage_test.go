@@ -0,0 +1,78 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCoverageCurveBreakEven(t *testing.T) {
+	// One instance always running and a second one running half of the hours
+	hourlyCounts := []int{1, 1, 2, 2}
+
+	tests := []struct {
+		name            string
+		reservedMonthly float64
+		wantOptimal     int
+		wantSavings     float64
+	}{
+		{"reserve the instance running above the break-even", 60, 1, 480},
+		{"reserve both below the break-even", 40, 2, 840},
+		{"reserve none above the on-demand price", 110, 0, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			curve := coverageCurve(hourlyCounts, 100, test.reservedMonthly, 12)
+			if curve.PeakInstances != 2 || curve.MeanInstances != 1.5 || len(curve.Points) != 3 {
+				t.Fatalf("got peak %d, mean %.2f and %d points, want 2, 1.50 and 3", curve.PeakInstances, curve.MeanInstances, len(curve.Points))
+			}
+			if curve.Optimal != test.wantOptimal {
+				t.Errorf("got optimal quantity %d, want %d", curve.Optimal, test.wantOptimal)
+			}
+			if got := curve.Points[curve.Optimal].Savings; math.Abs(got-test.wantSavings) > 1e-9 {
+				t.Errorf("got savings %.2f, want %.2f", got, test.wantSavings)
+			}
+		})
+	}
+
+	curve := coverageCurve(hourlyCounts, 100, 60, 12)
+	if got := curve.Points[1].CoveragePercent; math.Abs(got-400.0/6) > 1e-9 {
+		t.Errorf("got a coverage of %.2f%% for one reservation, want 66.67%%", got)
+	}
+	if got := curve.Points[2].UtilizationPercent; got != 75 {
+		t.Errorf("got a utilization of %.2f%% for two reservations, want 75%%", got)
+	}
+}
+
+func TestCoverageOptionsSkipsNotOffered(t *testing.T) {
+	row := func(term, paymentOption string, monthly float64, status string) PricingData {
+		return PricingData{
+			InstanceType:                    "db.m5.large",
+			Engine:                          "PostgreSQL",
+			Term:                            term,
+			OfferingClass:                   "Standard",
+			PaymentOption:                   paymentOption,
+			AmortizedMonthlyCostPerInstance: monthly,
+			OfferingStatus:                  status,
+		}
+	}
+	data := []PricingData{
+		row("On-Demand", "N/A", 130, ""),
+		row("1 Year", "No Upfront", 90, OfferingStatusOffered),
+		row("1 Year", "All Upfront", 70, OfferingStatusNotOffered),
+		row("1 Year", "Partial Upfront", 80, OfferingStatusPriceMismatch),
+		{InstanceType: "db.m5.large", Engine: "MySQL", Term: "1 Year", AmortizedMonthlyCostPerInstance: 10},
+	}
+
+	onDemand, reserved := coverageOptions(data, forecastKey{"db.m5.large", "PostgreSQL"})
+	if onDemand == nil || onDemand.AmortizedMonthlyCostPerInstance != 130 {
+		t.Fatalf("got on-demand row %+v, want the 130 one", onDemand)
+	}
+	if reserved == nil || reserved.PaymentOption != "Partial Upfront" {
+		t.Fatalf("got reserved row %+v, want the cheapest offered Partial Upfront one", reserved)
+	}
+
+	data[2].OfferingStatus = ""
+	if _, reserved := coverageOptions(data, forecastKey{"db.m5.large", "PostgreSQL"}); reserved.PaymentOption != "All Upfront" {
+		t.Errorf("got %s, want the unvalidated All Upfront option", reserved.PaymentOption)
+	}
+}
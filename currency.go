@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	SourceCurrency = "USD" // Currency of the pricing data, depending on the partition
+	Currency       = SourceCurrency
+	ExchangeRate   = 1.0 // Units of Currency per unit of SourceCurrency
+)
+
+// ExchangeRatesProvider returns how many units of a currency one US dollar buys.
+type ExchangeRatesProvider interface {
+	Rate(currency string) (float64, error)
+}
+
+// FileRatesProvider reads the exchange rates from a JSON or YAML file mapping
+// currency codes to their rate per US dollar, such as {"EUR": 0.92, "GBP": 0.79}.
+type FileRatesProvider struct {
+	Path string
+}
+
+func (p FileRatesProvider) Rate(currency string) (float64, error) {
+	body, err := os.ReadFile(p.Path)
+	if err != nil {
+		return 0, err
+	}
+
+	var rates map[string]float64
+	if err := yaml.Unmarshal(body, &rates); err != nil {
+		return 0, fmt.Errorf("parsing exchange rates %s: %w", p.Path, err)
+	}
+
+	rate, ok := rates[currency]
+	if !ok || rate <= 0 {
+		return 0, fmt.Errorf("no exchange rate for %s in %s", currency, p.Path)
+	}
+	return rate, nil
+}
+
+// SetCurrency selects the currency of the pricing data and the one of the reported
+// costs, defaulting to the pricing one. Both exchange rates per US dollar are taken
+// from the provider to convert between other currencies.
+func SetCurrency(source, currency string, provider ExchangeRatesProvider) error {
+	SourceCurrency = source
+	currency = strings.ToUpper(currency)
+	if currency == "" || currency == source {
+		Currency, ExchangeRate = source, 1
+		return nil
+	}
+	if provider == nil {
+		return fmt.Errorf("reporting in %s requires exchange rates", currency)
+	}
+
+	rates := make(map[string]float64)
+	for _, code := range []string{source, currency} {
+		rates[code] = 1
+		if code != "USD" {
+			rate, err := provider.Rate(code)
+			if err != nil {
+				return err
+			}
+			rates[code] = rate
+		}
+	}
+	Currency, ExchangeRate = currency, rates[currency]/rates[source]
+	debugLog.Printf("Reporting costs in %s at %f per %s", Currency, ExchangeRate, SourceCurrency)
+	return nil
+}
+
+// formatCost formats an amount of the pricing data in the reporting currency.
+func formatCost(amount float64) string {
+	return fmt.Sprintf("%.2f", amount*ExchangeRate)
+}
+
+// currencyHeaders labels the cost columns of a table with the reporting currency.
+func currencyHeaders(headers []string) []string {
+	if Currency == "USD" {
+		return headers
+	}
+	labelled := make([]string, len(headers))
+	for i, header := range headers {
+		labelled[i] = strings.ReplaceAll(header, "($)", fmt.Sprintf("(%s)", Currency))
+	}
+	return labelled
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// mapRatesProvider serves fixed exchange rates per US dollar.
+type mapRatesProvider map[string]float64
+
+func (p mapRatesProvider) Rate(currency string) (float64, error) {
+	rate, ok := p[currency]
+	if !ok {
+		return 0, os.ErrNotExist
+	}
+	return rate, nil
+}
+
+// resetCurrency reports the costs in US dollars again once the test is done.
+func resetCurrency(t *testing.T) {
+	t.Cleanup(func() {
+		if err := SetCurrency("USD", "", nil); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestSetCurrency(t *testing.T) {
+	rates := mapRatesProvider{"EUR": 0.92, "GBP": 0.8, "CNY": 7.2}
+	tests := []struct {
+		name         string
+		source       string
+		currency     string
+		provider     ExchangeRatesProvider
+		wantCurrency string
+		wantRate     float64
+		wantErr      bool
+	}{
+		{"pricing currency by default", "USD", "", nil, "USD", 1, false},
+		{"China pricing currency by default", "CNY", "", nil, "CNY", 1, false},
+		{"same currency as the pricing", "USD", "usd", nil, "USD", 1, false},
+		{"from US dollars", "USD", "eur", rates, "EUR", 0.92, false},
+		{"to US dollars", "CNY", "USD", rates, "USD", 1 / 7.2, false},
+		{"between two other currencies", "CNY", "GBP", rates, "GBP", 0.8 / 7.2, false},
+		{"without exchange rates", "USD", "EUR", nil, "", 0, true},
+		{"without the currency rate", "USD", "JPY", rates, "", 0, true},
+		{"without the pricing currency rate", "CNY", "JPY", mapRatesProvider{"JPY": 150}, "", 0, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resetCurrency(t)
+			err := SetCurrency(test.source, test.currency, test.provider)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("got error %v, want error: %t", err, test.wantErr)
+			}
+			if test.wantErr {
+				return
+			}
+			if Currency != test.wantCurrency || math.Abs(ExchangeRate-test.wantRate) > 1e-12 {
+				t.Errorf("got %s at %f, want %s at %f", Currency, ExchangeRate, test.wantCurrency, test.wantRate)
+			}
+		})
+	}
+}
+
+func TestFormatCostConvertsAndRounds(t *testing.T) {
+	resetCurrency(t)
+	tests := []struct {
+		currency string
+		amount   float64
+		want     string
+	}{
+		{"USD", 100.0 / 3, "33.33"},
+		{"USD", 2.0 / 3, "0.67"},
+		{"USD", 0, "0.00"},
+		{"USD", -12.344, "-12.34"},
+		{"EUR", 1000, "920.00"},
+		{"EUR", 1.25, "1.15"},
+		{"GBP", 0.01, "0.01"},
+		{"GBP", 0.006, "0.00"},
+	}
+	for _, test := range tests {
+		if err := SetCurrency("USD", test.currency, mapRatesProvider{"EUR": 0.92, "GBP": 0.8}); err != nil {
+			t.Fatal(err)
+		}
+		if got := formatCost(test.amount); got != test.want {
+			t.Errorf("%f USD in %s: got %s, want %s", test.amount, test.currency, got, test.want)
+		}
+	}
+}
+
+func TestCurrencyHeaders(t *testing.T) {
+	resetCurrency(t)
+	headers := []string{"Instance Type", "Upfront Cost ($)", "Savings (%)"}
+	if got := currencyHeaders(headers); got[1] != "Upfront Cost ($)" {
+		t.Errorf("got %q in US dollars, want the header unchanged", got[1])
+	}
+
+	if err := SetCurrency("USD", "EUR", mapRatesProvider{"EUR": 0.92}); err != nil {
+		t.Fatal(err)
+	}
+	got := currencyHeaders(headers)
+	if got[0] != "Instance Type" || got[1] != "Upfront Cost (EUR)" || got[2] != "Savings (%)" {
+		t.Errorf("got headers %q", got)
+	}
+}
+
+func TestFileRatesProvider(t *testing.T) {
+	dir := t.TempDir()
+	for name, body := range map[string]string{
+		"rates.json": `{"EUR": 0.92, "GBP": 0.79, "XXX": 0}`,
+		"rates.yaml": "EUR: 0.92\nGBP: 0.79\nXXX: 0\n",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		provider := FileRatesProvider{Path: path}
+
+		if rate, err := provider.Rate("GBP"); err != nil || rate != 0.79 {
+			t.Errorf("%s: got %f, %v, want 0.79", name, rate, err)
+		}
+		for _, currency := range []string{"JPY", "XXX"} {
+			if _, err := provider.Rate(currency); err == nil {
+				t.Errorf("%s: got a rate for %s", name, currency)
+			}
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DiscountConfig holds the discounts applied on top of the list prices, such as an
+// Enterprise Discount Program (EDP) percentage and private pricing addendums. A
+// region override takes precedence over a service one, itself taking precedence
+// over the global percentage.
+//
+// The service overrides are keyed by the AWS name of the service: RDS, EC2,
+// ElastiCache, OpenSearch, Redshift and MemoryDB. DocumentDB and Neptune, priced in
+// the RDS mode, take their own DocumentDB and Neptune keys, while the Oracle and SQL
+// Server instances take the RDS one.
+type DiscountConfig struct {
+	Percent  float64            `yaml:"percent"`
+	Services map[string]float64 `yaml:"services"` // By service name, see discountServices
+	Regions  map[string]float64 `yaml:"regions"`
+}
+
+// discountServices returns the sorted keys accepted by the service overrides.
+func discountServices() []string {
+	keys := make(map[string]bool)
+	for _, name := range serviceNames {
+		keys[name] = true
+	}
+	for _, engine := range priceListEngines {
+		keys[engine.Service] = true
+	}
+
+	services := make([]string, 0, len(keys))
+	for key := range keys {
+		services = append(services, key)
+	}
+	sort.Strings(services)
+	return services
+}
+
+var Discounts DiscountConfig
+
+// LoadDiscountConfig reads a JSON or YAML discount configuration file.
+func LoadDiscountConfig(path string) (DiscountConfig, error) {
+	var discounts DiscountConfig
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return discounts, err
+	}
+	if err := yaml.Unmarshal(body, &discounts); err != nil {
+		return discounts, fmt.Errorf("parsing discounts %s: %w", path, err)
+	}
+
+	services := discountServices()
+	percents := map[string]float64{"percent": discounts.Percent}
+	for service, percent := range discounts.Services {
+		if i := sort.SearchStrings(services, service); i == len(services) || services[i] != service {
+			return discounts, fmt.Errorf("services.%s: unknown service, expected one of %s", service, strings.Join(services, ", "))
+		}
+		percents["services."+service] = percent
+	}
+	for region, percent := range discounts.Regions {
+		percents["regions."+region] = percent
+	}
+	for key, percent := range percents {
+		if percent < 0 || percent >= 100 {
+			return discounts, fmt.Errorf("%s: discount of %.2f%% is outside of the 0-100%% range", key, percent)
+		}
+	}
+	return discounts, nil
+}
+
+// DiscountPercent returns the discount applying to the prices of a service in a region.
+func (c DiscountConfig) DiscountPercent(service, region string) float64 {
+	if percent, ok := c.Regions[region]; ok {
+		return percent
+	}
+	if percent, ok := c.Services[service]; ok {
+		return percent
+	}
+	return c.Percent
+}
+
+// discountFactor returns the share of the list price paid for a service in a region.
+func discountFactor(service, region string) float64 {
+	return 1 - Discounts.DiscountPercent(service, region)/100
+}
+
+// listPrice returns the list price of an amount discounted by the given percentage.
+func listPrice(amount, discountPercent float64) float64 {
+	return amount / (1 - discountPercent/100)
+}
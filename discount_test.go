@@ -0,0 +1,32 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadDiscountConfigServices(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantErr bool
+	}{
+		{"service names", "services:\n  RDS: 12\n  EC2: 5\n  MemoryDB: 3\n", false},
+		{"DocumentDB and Neptune engines", "services:\n  DocumentDB: 4\n  Neptune: 6\n", false},
+		{"lowercase service", "services:\n  rds: 12\n", true},
+		{"engine name", "services:\n  PostgreSQL: 12\n", true},
+		{"percent out of range", "services:\n  RDS: 120\n", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "discounts.yaml")
+			if err := os.WriteFile(path, []byte(test.body), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := LoadDiscountConfig(path); (err != nil) != test.wantErr {
+				t.Errorf("got error %v, want error: %t", err, test.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,343 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	ec2instancesinfo "github.com/LeanerCloud/ec2-instances-info"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// Platforms of the EC2 instances, each priced differently.
+const (
+	PlatformLinux   = "Linux"
+	PlatformWindows = "Windows"
+	PlatformRHEL    = "RHEL"
+	PlatformSUSE    = "SUSE"
+)
+
+// Tenancies of the EC2 instances, the shared one being priced from the
+// ec2instances.info data and the dedicated one from the AWS Price List API.
+const (
+	TenancyDefault   = "default"
+	TenancyDedicated = "dedicated"
+	TenancyHost      = "host"
+)
+
+// The EC2 pricing data is large, so it's only loaded once.
+var ec2Data *ec2instancesinfo.InstanceData
+
+// ec2PricingData returns the EC2 instance types and their prices.
+func ec2PricingData() (*ec2instancesinfo.InstanceData, error) {
+	if ec2Data != nil {
+		return ec2Data, nil
+	}
+	data, err := ec2instancesinfo.Data()
+	if err != nil {
+		errorLog.Printf("Error fetching EC2 data: %v", err)
+		return nil, err
+	}
+	ec2Data = data
+	return ec2Data, nil
+}
+
+// GetRunningEC2Instances fetches the EC2 instances whose state is one of the given
+// states. The others, as well as the instances reservations can't be priced for,
+// are returned as excluded instances.
+func GetRunningEC2Instances(region string, states []string) ([]InstanceInfo, []ExcludedInstance, error) {
+	cfg, err := config.LoadDefaultConfig(context.TODO(), config.WithRegion(region))
+	if err != nil {
+		errorLog.Printf("Error loading AWS config: %v", err)
+		return nil, nil, err
+	}
+
+	svc := ec2.NewFromConfig(cfg)
+	paginator := ec2.NewDescribeInstancesPaginator(svc, &ec2.DescribeInstancesInput{})
+
+	var instances []InstanceInfo
+	var excluded []ExcludedInstance
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			errorLog.Printf("Error describing EC2 instances: %v", err)
+			return nil, nil, err
+		}
+
+		for _, reservation := range page.Reservations {
+			for _, instance := range reservation.Instances {
+				info := InstanceInfo{
+					InstanceType:      string(instance.InstanceType),
+					NumberOfInstances: 1,
+					Engine:            ec2Platform(instance),
+					Tags:              tagsFromEC2Tags(instance.Tags),
+					Identifier:        aws.ToString(instance.InstanceId),
+					ARN: fmt.Sprintf("arn:%s:ec2:%s:%s:instance/%s",
+						regionPartition(region), region, aws.ToString(reservation.OwnerId), aws.ToString(instance.InstanceId)),
+					CreationTime: aws.ToTime(instance.LaunchTime),
+					Tenancy:      TenancyDefault,
+				}
+				if instance.State != nil {
+					info.Status = string(instance.State.Name)
+				}
+				if instance.Placement != nil {
+					info.AvailabilityZone = aws.ToString(instance.Placement.AvailabilityZone)
+					if instance.Placement.Tenancy != "" {
+						info.Tenancy = string(instance.Placement.Tenancy)
+					}
+				}
+
+				reason := ""
+				switch {
+				case !isStatusIncluded(info.Status, states):
+					reason = "Status not included by the status policy"
+				case instance.InstanceLifecycle != "":
+					reason = fmt.Sprintf("%s instances aren't covered by reservations", instance.InstanceLifecycle)
+				case info.Tenancy == TenancyHost:
+					reason = "Dedicated Host instances are billed by the host, not covered by instance reservations"
+				case info.Tenancy != TenancyDefault && info.Tenancy != TenancyDedicated:
+					reason = fmt.Sprintf("No pricing data for the %s tenancy", info.Tenancy)
+				case info.Engine == "":
+					info.Engine = aws.ToString(instance.PlatformDetails)
+					reason = "Platform not supported"
+				default:
+					info.Engine = ec2Engine(info.Engine, info.Tenancy)
+				}
+				if reason != "" {
+					excluded = append(excluded, ExcludedInstance{
+						Identifier:   info.Identifier,
+						InstanceType: info.InstanceType,
+						Engine:       info.Engine,
+						Status:       info.Status,
+						Reason:       reason,
+					})
+					continue
+				}
+
+				instances = append(instances, info)
+			}
+		}
+	}
+
+	debugLog.Printf("Found running EC2 instances: %v", instances)
+	debugLog.Printf("Excluded EC2 instances: %v", excluded)
+	return instances, excluded, nil
+}
+
+// The engine suffix of the Dedicated Instances, priced apart from the shared ones
+// of their platform.
+const dedicatedEngineSuffix = " Dedicated"
+
+// ec2Engine returns the engine the EC2 instances are grouped and priced by, their
+// platform, qualified for the Dedicated Instances.
+func ec2Engine(platform, tenancy string) string {
+	if tenancy == TenancyDedicated {
+		return platform + dedicatedEngineSuffix
+	}
+	return platform
+}
+
+// ec2EnginePlatform returns the platform of an EC2 engine.
+func ec2EnginePlatform(engine string) string {
+	return strings.TrimSuffix(engine, dedicatedEngineSuffix)
+}
+
+// ec2Platform returns the platform an instance is billed for, or an empty string
+// for the platforms that aren't supported, such as the SQL Server ones.
+func ec2Platform(instance ec2types.Instance) string {
+	switch aws.ToString(instance.PlatformDetails) {
+	case "Linux/UNIX":
+		return PlatformLinux
+	case "Windows":
+		return PlatformWindows
+	case "Red Hat Enterprise Linux":
+		return PlatformRHEL
+	case "SUSE Linux":
+		return PlatformSUSE
+	case "":
+		if instance.Platform == ec2types.PlatformValuesWindows {
+			return PlatformWindows
+		}
+		return PlatformLinux
+	}
+	return ""
+}
+
+// ec2PlatformPricing returns the pricing of the given platform from the regional EC2 prices.
+func ec2PlatformPricing(prices ec2instancesinfo.RegionPrices, platform string) ec2instancesinfo.Pricing {
+	switch platform {
+	case PlatformLinux:
+		return prices.Linux
+	case PlatformWindows:
+		return prices.MSWin
+	case PlatformRHEL:
+		return prices.RHEL
+	case PlatformSUSE:
+		return prices.SLES
+	}
+	return ec2instancesinfo.Pricing{}
+}
+
+// ec2ReservedOptions lists the standard and convertible reserved options of an EC2 pricing.
+func ec2ReservedOptions(pricing ec2instancesinfo.Pricing) []reservedOption {
+	return []reservedOption{
+		{"yrTerm1Standard.noUpfront", pricing.Reserved.StandardNoUpfront1Year},
+		{"yrTerm3Standard.noUpfront", pricing.Reserved.StandardNoUpfront3Years},
+		{"yrTerm1Standard.partialUpfront", pricing.Reserved.StandardPartiallUpfront1Year},
+		{"yrTerm3Standard.partialUpfront", pricing.Reserved.StandardPartialUpfront3Years},
+		{"yrTerm1Standard.allUpfront", pricing.Reserved.StandardAllUpfront1Year},
+		{"yrTerm3Standard.allUpfront", pricing.Reserved.StandardAllUpfront3Years},
+		{"yrTerm1Convertible.noUpfront", pricing.Reserved.ConvertibleNoUpfront1Year},
+		{"yrTerm3Convertible.noUpfront", pricing.Reserved.ConvertibleNoUpfront3Years},
+		{"yrTerm1Convertible.partialUpfront", pricing.Reserved.ConvertiblePartiallUpfront1Year},
+		{"yrTerm3Convertible.partialUpfront", pricing.Reserved.ConvertiblePartialUpfront3Years},
+		{"yrTerm1Convertible.allUpfront", pricing.Reserved.ConvertibleAllUpfront1Year},
+		{"yrTerm3Convertible.allUpfront", pricing.Reserved.ConvertibleAllUpfront3Years},
+	}
+}
+
+// ec2PriceListReservedOptions lists the standard and convertible reserved options of
+// an EC2 instance type priced by the AWS Price List API.
+func ec2PriceListReservedOptions(pricing priceListInstancePricing) []reservedOption {
+	options := priceListReservedOptions(pricing)
+	for _, term := range []string{
+		"yrTerm1Convertible.noUpfront",
+		"yrTerm3Convertible.noUpfront",
+		"yrTerm1Convertible.partialUpfront",
+		"yrTerm3Convertible.partialUpfront",
+		"yrTerm1Convertible.allUpfront",
+		"yrTerm3Convertible.allUpfront",
+	} {
+		options = append(options, reservedOption{term, pricing.Reserved[term]})
+	}
+	return options
+}
+
+// The Dedicated Instances prices are only fetched once per instance type, platform
+// and region.
+var ec2DedicatedData = make(map[string]priceListInstancePricing)
+
+// ec2DedicatedPricing returns the prices of the Dedicated Instances of an instance
+// type and platform from the AWS Price List API, without the hourly fee charged per
+// region while any Dedicated Instance runs.
+func ec2DedicatedPricing(region, instanceType, platform string) (priceListInstancePricing, error) {
+	key := fmt.Sprintf("%s-%s-%s", instanceType, platform, region)
+	if pricing, ok := ec2DedicatedData[key]; ok {
+		return pricing, nil
+	}
+	products, err := getPriceListProducts(region, "AmazonEC2", map[string]string{
+		"instanceType":    instanceType,
+		"operatingSystem": platform,
+		"tenancy":         "Dedicated",
+		"preInstalledSw":  "NA",
+		"licenseModel":    "No License required",
+		"capacitystatus":  "Used",
+	})
+	if err != nil {
+		return priceListInstancePricing{}, err
+	}
+
+	pricing := priceListInstanceTypes(products, pricingCurrency(region), func(priceListProduct) bool {
+		return true
+	})[instanceType]
+	ec2DedicatedData[key] = pricing
+	return pricing, nil
+}
+
+// ProcessEC2PricingData returns the on-demand and reserved pricing rows of each
+// instance type and engine of the aggregated EC2 instances, for both terms.
+func ProcessEC2PricingData(region string, runningInstances []InstanceInfo) ([]PricingData, []PricingData) {
+	instanceTypes, err := ec2PricingData()
+	if err != nil {
+		errorLog.Printf("Failed to fetch EC2 data: %v", err)
+		return nil, nil
+	}
+
+	prices := make(map[string]ec2instancesinfo.RegionPrices)
+	for _, instanceType := range *instanceTypes {
+		if regionPrices, ok := instanceType.Pricing[region]; ok {
+			prices[instanceType.InstanceType] = regionPrices
+		}
+	}
+
+	discount := discountFactor(serviceName(ServiceEC2), region)
+	discountPercent := Discounts.DiscountPercent(serviceName(ServiceEC2), region)
+
+	processed := make(map[string]bool)
+	var finalData1Year, finalData3Years []PricingData
+	for _, instance := range runningInstances {
+		key := fmt.Sprintf("%s-%s", instance.InstanceType, instance.Engine)
+		if processed[key] {
+			continue
+		}
+		processed[key] = true
+
+		var onDemand float64
+		var options []reservedOption
+		if instance.Tenancy == TenancyDedicated {
+			pricing, err := ec2DedicatedPricing(region, instance.InstanceType, ec2EnginePlatform(instance.Engine))
+			if err != nil {
+				errorLog.Printf("Failed to fetch the %s Dedicated Instances data: %v", instance.InstanceType, err)
+				continue
+			}
+			onDemand, options = pricing.OnDemand, ec2PriceListReservedOptions(pricing)
+		} else {
+			pricing := ec2PlatformPricing(prices[instance.InstanceType], instance.Engine)
+			onDemand, options = pricing.OnDemand, ec2ReservedOptions(pricing)
+		}
+		if onDemand == 0 {
+			debugLog.Printf("No %s pricing for %s in %s", instance.Engine, instance.InstanceType, region)
+			continue
+		}
+
+		onDemand1Year, onDemand3Years := onDemandRows(instance.InstanceType, region, onDemand*discount, instance.NumberOfInstances)
+		reserved1Year, reserved3Years := reservedRows(instance.InstanceType, options, onDemand, discount, instance.NumberOfInstances)
+		data1Year := append([]PricingData{onDemand1Year}, reserved1Year...)
+		data3Years := append([]PricingData{onDemand3Years}, reserved3Years...)
+
+		for _, data := range [][]PricingData{data1Year, data3Years} {
+			for i := range data {
+				data[i].Engine = instance.Engine
+				data[i].Tenancy = instance.Tenancy
+				data[i].DiscountPercent = discountPercent
+			}
+		}
+		finalData1Year = append(finalData1Year, data1Year...)
+		finalData3Years = append(finalData3Years, data3Years...)
+	}
+
+	debugLog.Printf("EC2 Data 1 year: %v", finalData1Year)
+	debugLog.Printf("EC2 Data 3 years: %v", finalData3Years)
+	return finalData1Year, finalData3Years
+}
+
+// ec2Columns adapts the pricing table columns to EC2 instances, showing their
+// platform and tenancy, and the offering class as both standard and convertible
+// reservations are sold.
+func ec2Columns(columns []string) []string {
+	var adapted []string
+	for _, column := range columns {
+		switch column {
+		case "Instance Type":
+			adapted = append(adapted, column, "Platform", "Tenancy")
+		case "Term":
+			adapted = append(adapted, column, "Offering Class")
+		case "Databases":
+			adapted = append(adapted, "Instances")
+		default:
+			adapted = append(adapted, column)
+		}
+	}
+	return adapted
+}
+
+func tagsFromEC2Tags(tagList []ec2types.Tag) map[string]string {
+	tags := make(map[string]string, len(tagList))
+	for _, tag := range tagList {
+		tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	return tags
+}
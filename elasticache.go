@@ -0,0 +1,265 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	ec2instancesinfo "github.com/LeanerCloud/ec2-instances-info"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/elasticache"
+	elasticachetypes "github.com/aws/aws-sdk-go-v2/service/elasticache/types"
+)
+
+// Cache engines, as shown in the pricing tables.
+const (
+	EngineRedis     = "Redis"
+	EngineValkey    = "Valkey"
+	EngineMemcached = "Memcached"
+)
+
+// AWS prices the on-demand and reserved Valkey nodes 20% lower than the Redis OSS
+// ones of the same node type, as stated on https://aws.amazon.com/elasticache/pricing/
+// since the Valkey launch of October 2024. The pricing data doesn't list the Valkey
+// prices yet, so they're derived from the Redis OSS ones.
+const valkeyPriceFactor = 0.8
+
+// The ElastiCache pricing data is only loaded once.
+var elastiCacheData *ec2instancesinfo.ElastiCacheInstanceData
+
+// elastiCachePricingData returns the ElastiCache node types and their prices.
+func elastiCachePricingData() (*ec2instancesinfo.ElastiCacheInstanceData, error) {
+	if elastiCacheData != nil {
+		return elastiCacheData, nil
+	}
+	data, err := ec2instancesinfo.ElastiCacheData()
+	if err != nil {
+		errorLog.Printf("Error fetching ElastiCache data: %v", err)
+		return nil, err
+	}
+	elastiCacheData = data
+	return elastiCacheData, nil
+}
+
+// GetRunningElastiCacheNodes fetches the ElastiCache replication groups and the
+// cache clusters outside of them whose status is one of the given statuses, each
+// entry counting its nodes. The others are returned as excluded instances.
+func GetRunningElastiCacheNodes(region string, statuses []string) ([]InstanceInfo, []ExcludedInstance, error) {
+	cfg, err := config.LoadDefaultConfig(context.TODO(), config.WithRegion(region))
+	if err != nil {
+		errorLog.Printf("Error loading AWS config: %v", err)
+		return nil, nil, err
+	}
+	svc := elasticache.NewFromConfig(cfg)
+
+	clusters := make(map[string]elasticachetypes.CacheCluster)
+	clusterPaginator := elasticache.NewDescribeCacheClustersPaginator(svc, &elasticache.DescribeCacheClustersInput{})
+	for clusterPaginator.HasMorePages() {
+		page, err := clusterPaginator.NextPage(context.TODO())
+		if err != nil {
+			errorLog.Printf("Error describing cache clusters: %v", err)
+			return nil, nil, err
+		}
+		for _, cluster := range page.CacheClusters {
+			clusters[aws.ToString(cluster.CacheClusterId)] = cluster
+		}
+	}
+
+	var candidates []InstanceInfo
+	groupPaginator := elasticache.NewDescribeReplicationGroupsPaginator(svc, &elasticache.DescribeReplicationGroupsInput{})
+	for groupPaginator.HasMorePages() {
+		page, err := groupPaginator.NextPage(context.TODO())
+		if err != nil {
+			errorLog.Printf("Error describing replication groups: %v", err)
+			return nil, nil, err
+		}
+		for _, group := range page.ReplicationGroups {
+			candidates = append(candidates, replicationGroupInfo(group, clusters))
+		}
+	}
+
+	for _, cluster := range clusters {
+		if aws.ToString(cluster.ReplicationGroupId) != "" {
+			continue // Counted with its replication group
+		}
+		candidates = append(candidates, InstanceInfo{
+			InstanceType:      aws.ToString(cluster.CacheNodeType),
+			NumberOfInstances: int(aws.ToInt32(cluster.NumCacheNodes)),
+			Engine:            cacheEngine(aws.ToString(cluster.Engine)),
+			Status:            aws.ToString(cluster.CacheClusterStatus),
+			Identifier:        aws.ToString(cluster.CacheClusterId),
+			ARN:               aws.ToString(cluster.ARN),
+			EngineVersion:     aws.ToString(cluster.EngineVersion),
+			AvailabilityZone:  aws.ToString(cluster.PreferredAvailabilityZone),
+			CreationTime:      aws.ToTime(cluster.CacheClusterCreateTime),
+		})
+	}
+
+	var instances []InstanceInfo
+	var excluded []ExcludedInstance
+	for _, candidate := range candidates {
+		if !isStatusIncluded(candidate.Status, statuses) {
+			excluded = append(excluded, ExcludedInstance{
+				Identifier:   candidate.Identifier,
+				InstanceType: candidate.InstanceType,
+				Engine:       candidate.Engine,
+				Status:       candidate.Status,
+				Reason:       "Status not included by the status policy",
+			})
+			continue
+		}
+
+		// Listing the tags takes a call per cluster, only do it when they're used
+		if len(FilterTags) > 0 || GroupByTag != "" {
+			tags, err := svc.ListTagsForResource(context.TODO(), &elasticache.ListTagsForResourceInput{
+				ResourceName: aws.String(candidate.ARN),
+			})
+			if err != nil {
+				errorLog.Printf("Error listing the tags of %s: %v", candidate.Identifier, err)
+				return nil, nil, err
+			}
+			candidate.Tags = tagsFromElastiCacheTags(tags.TagList)
+		}
+		instances = append(instances, candidate)
+	}
+
+	debugLog.Printf("Found running cache nodes: %v", instances)
+	debugLog.Printf("Excluded cache nodes: %v", excluded)
+	return instances, excluded, nil
+}
+
+// replicationGroupInfo describes a replication group, counting one node per member cluster.
+func replicationGroupInfo(group elasticachetypes.ReplicationGroup, clusters map[string]elasticachetypes.CacheCluster) InstanceInfo {
+	info := InstanceInfo{
+		InstanceType:      aws.ToString(group.CacheNodeType),
+		NumberOfInstances: len(group.MemberClusters),
+		Engine:            cacheEngine(aws.ToString(group.Engine)),
+		MultiAZ:           group.MultiAZ == elasticachetypes.MultiAZStatusEnabled,
+		Status:            aws.ToString(group.Status),
+		Identifier:        aws.ToString(group.ReplicationGroupId),
+		ARN:               aws.ToString(group.ARN),
+		CreationTime:      aws.ToTime(group.ReplicationGroupCreateTime),
+	}
+
+	zones := make(map[string]bool)
+	for _, member := range group.MemberClusters {
+		cluster, ok := clusters[member]
+		if !ok {
+			continue
+		}
+		if info.EngineVersion == "" {
+			info.EngineVersion = aws.ToString(cluster.EngineVersion)
+		}
+		if aws.ToString(group.Engine) == "" {
+			info.Engine = cacheEngine(aws.ToString(cluster.Engine))
+		}
+		zones[aws.ToString(cluster.PreferredAvailabilityZone)] = true
+	}
+
+	var sortedZones []string
+	for zone := range zones {
+		sortedZones = append(sortedZones, zone)
+	}
+	sort.Strings(sortedZones)
+	info.AvailabilityZone = strings.Join(sortedZones, ", ")
+	return info
+}
+
+// cacheEngine maps the ElastiCache API engine names to the pricing table ones.
+func cacheEngine(engine string) string {
+	switch strings.ToLower(engine) {
+	case "memcached":
+		return EngineMemcached
+	case "valkey":
+		return EngineValkey
+	}
+	return EngineRedis // Replication groups are Redis OSS unless stated otherwise
+}
+
+// cacheEnginePricing returns the pricing of the given engine from the regional
+// ElastiCache prices, along with the factor to apply to its prices.
+func cacheEnginePricing(prices ec2instancesinfo.ServicePricing, engine string) (ec2instancesinfo.PricingDetail, float64) {
+	switch engine {
+	case EngineMemcached:
+		return prices.Memcached, 1
+	case EngineValkey:
+		return prices.Redis, valkeyPriceFactor
+	}
+	return prices.Redis, 1
+}
+
+// elastiCacheReservedOptions lists the reserved options of an ElastiCache pricing.
+func elastiCacheReservedOptions(pricing ec2instancesinfo.PricingDetail, factor float64) []reservedOption {
+	return []reservedOption{
+		{"yrTerm1Standard.noUpfront", pricing.Reserved.YrTerm1StandardNoUpfront * factor},
+		{"yrTerm3Standard.noUpfront", pricing.Reserved.YrTerm3StandardNoUpfront * factor},
+		{"yrTerm1Standard.partialUpfront", pricing.Reserved.YrTerm1StandardPartialUpfront * factor},
+		{"yrTerm3Standard.partialUpfront", pricing.Reserved.YrTerm3StandardPartialUpfront * factor},
+		{"yrTerm3Standard.allUpfront", pricing.Reserved.YrTerm3StandardAllUpfront * factor},
+	}
+}
+
+// ProcessElastiCachePricingData returns the on-demand and reserved pricing rows of
+// each node type and engine of the aggregated cache nodes, for both terms.
+func ProcessElastiCachePricingData(region string, runningInstances []InstanceInfo) ([]PricingData, []PricingData) {
+	nodeTypes, err := elastiCachePricingData()
+	if err != nil {
+		errorLog.Printf("Failed to fetch ElastiCache data: %v", err)
+		return nil, nil
+	}
+
+	prices := make(map[string]ec2instancesinfo.ServicePricing)
+	for _, nodeType := range *nodeTypes {
+		if regionPrices, ok := nodeType.Pricing[region]; ok {
+			prices[nodeType.InstanceType] = regionPrices
+		}
+	}
+
+	discount := discountFactor(serviceName(ServiceElastiCache), region)
+	discountPercent := Discounts.DiscountPercent(serviceName(ServiceElastiCache), region)
+
+	processed := make(map[string]bool)
+	var finalData1Year, finalData3Years []PricingData
+	for _, instance := range runningInstances {
+		key := fmt.Sprintf("%s-%s", instance.InstanceType, instance.Engine)
+		if processed[key] {
+			continue
+		}
+		processed[key] = true
+
+		pricing, factor := cacheEnginePricing(prices[instance.InstanceType], instance.Engine)
+		if pricing.OnDemand == 0 {
+			debugLog.Printf("No %s pricing for %s in %s", instance.Engine, instance.InstanceType, region)
+			continue
+		}
+
+		onDemandHourly := pricing.OnDemand * factor
+		onDemand1Year, onDemand3Years := onDemandRows(instance.InstanceType, region, onDemandHourly*discount, instance.NumberOfInstances)
+		reserved1Year, reserved3Years := reservedRows(instance.InstanceType, elastiCacheReservedOptions(pricing, factor), onDemandHourly, discount, instance.NumberOfInstances)
+		data1Year := append([]PricingData{onDemand1Year}, reserved1Year...)
+		data3Years := append([]PricingData{onDemand3Years}, reserved3Years...)
+
+		for _, data := range [][]PricingData{data1Year, data3Years} {
+			for i := range data {
+				data[i].Engine = instance.Engine
+				data[i].DiscountPercent = discountPercent
+			}
+		}
+		finalData1Year = append(finalData1Year, data1Year...)
+		finalData3Years = append(finalData3Years, data3Years...)
+	}
+
+	debugLog.Printf("ElastiCache Data 1 year: %v", finalData1Year)
+	debugLog.Printf("ElastiCache Data 3 years: %v", finalData3Years)
+	return finalData1Year, finalData3Years
+}
+
+func tagsFromElastiCacheTags(tagList []elasticachetypes.Tag) map[string]string {
+	tags := make(map[string]string, len(tagList))
+	for _, tag := range tagList {
+		tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	return tags
+}
@@ -0,0 +1,373 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/olekukonko/tablewriter"
+	"gopkg.in/yaml.v3"
+)
+
+const forecastMonths = 36 // Forecasts cover the longest reservation term
+
+// ForecastScenario lists the expected changes of the fleet over the forecast months.
+type ForecastScenario struct {
+	Changes []ForecastChange `yaml:"changes"`
+}
+
+// ForecastChange adds instances of a type and engine from a month on, or removes
+// them when the count is negative. Months are numbered from 1.
+type ForecastChange struct {
+	Month        int    `yaml:"month"`
+	InstanceType string `yaml:"instance_type"`
+	Engine       string `yaml:"engine"`
+	Count        int    `yaml:"count"`
+}
+
+// ForecastPlan holds the reservation quantity minimizing the total cost of an
+// instance type and engine over one term of the forecast.
+type ForecastPlan struct {
+	InstanceType     string
+	Engine           string
+	Term             string
+	FirstMonth       int
+	LastMonth        int
+	InstancesToday   int
+	MinInstances     int
+	MaxInstances     int
+	Quantity         int
+	OfferingClass    string
+	PaymentOption    string
+	OnDemandCost     float64
+	OnDemandMonthly  float64 // On-demand cost of an instance for a month
+	CurrentCountCost float64 // Cost of reserving today's count for the term instead
+	RecommendedCost  float64
+	Savings          float64
+	SavingsPercent   float64
+	MonthlyInstances []int
+	MonthlyCosts     []float64 // Amortized cost of each month of the term
+}
+
+type forecastKey struct {
+	InstanceType string
+	Engine       string
+}
+
+// forecastOption is a reserved option of an instance type and engine.
+type forecastOption struct {
+	OfferingClass        string
+	PaymentOption        string
+	Months               int
+	CostForTerm          float64
+	AmortizedMonthlyCost float64
+}
+
+// LoadForecastScenario reads a JSON or YAML forecast scenario file.
+func LoadForecastScenario(path string) (*ForecastScenario, error) {
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var scenario ForecastScenario
+	if err := yaml.Unmarshal(body, &scenario); err != nil {
+		return nil, fmt.Errorf("parsing forecast scenario %s: %w", path, err)
+	}
+
+	for i, change := range scenario.Changes {
+		if change.Month < 1 || change.Month > forecastMonths {
+			return nil, fmt.Errorf("change %d: month %d is outside of the 1-%d forecast", i+1, change.Month, forecastMonths)
+		}
+		if change.InstanceType == "" || change.Engine == "" {
+			return nil, fmt.Errorf("change %d: instance_type and engine are required", i+1)
+		}
+		if engine := determineServiceFromDBEngine(&change.Engine); engine != "Unknown" {
+			scenario.Changes[i].Engine = engine // Accept the RDS API engine names too
+		}
+	}
+	return &scenario, nil
+}
+
+// forecastFleet returns the number of instances of each type and engine for every
+// month of the forecast, starting from the current instances.
+func forecastFleet(instances []InstanceInfo, scenario *ForecastScenario) (map[forecastKey][]int, error) {
+	fleet := make(map[forecastKey][]int)
+	monthlyCounts := func(key forecastKey) []int {
+		if _, ok := fleet[key]; !ok {
+			fleet[key] = make([]int, forecastMonths)
+		}
+		return fleet[key]
+	}
+
+	for _, instance := range instances {
+		counts := monthlyCounts(forecastKey{instance.InstanceType, instance.Engine})
+		for month := range counts {
+			counts[month] += instance.NumberOfInstances
+		}
+	}
+
+	for _, change := range scenario.Changes {
+		counts := monthlyCounts(forecastKey{change.InstanceType, change.Engine})
+		for month := change.Month - 1; month < forecastMonths; month++ {
+			counts[month] += change.Count
+			if counts[month] < 0 {
+				return nil, fmt.Errorf("the %s %s fleet drops below zero instances in month %d", change.Engine, change.InstanceType, month+1)
+			}
+		}
+	}
+	return fleet, nil
+}
+
+// ForecastReservations finds, for each instance type and engine and for both terms,
+// the reserved option and quantity minimizing the cost of the forecasted fleet.
+// 1 year reservations are planned separately for each year of the forecast.
+func ForecastReservations(region string, instances []InstanceInfo, scenario *ForecastScenario) ([]ForecastPlan, error) {
+	fleet, err := forecastFleet(instances, scenario)
+	if err != nil {
+		return nil, err
+	}
+
+	// Also price the types only added by the scenario
+	forecastInstances := append([]InstanceInfo{}, instances...)
+	for _, change := range scenario.Changes {
+		forecastInstances = append(forecastInstances, InstanceInfo{InstanceType: change.InstanceType, Engine: change.Engine})
+	}
+	data1Year, data3Years := ProcessPricingData(region, aggregateInstances(forecastInstances))
+	onDemandCosts, options := forecastPrices(data1Year, data3Years)
+
+	var keys []forecastKey
+	for key := range fleet {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].InstanceType != keys[j].InstanceType {
+			return keys[i].InstanceType < keys[j].InstanceType
+		}
+		return keys[i].Engine < keys[j].Engine
+	})
+
+	var plans []ForecastPlan
+	for _, key := range keys {
+		if _, ok := onDemandCosts[key]; !ok {
+			errorLog.Printf("No %s pricing found for %s, leaving it out of the forecast", key.Engine, key.InstanceType)
+			continue
+		}
+
+		today := getInstanceCount(key.InstanceType, key.Engine, instances)
+		for _, term := range []struct {
+			Name   string
+			Months int
+		}{{"1 Year", 12}, {"3 Year", 36}} {
+			var termOptions []forecastOption
+			for _, option := range options[key] {
+				if option.Months == term.Months {
+					termOptions = append(termOptions, option)
+				}
+			}
+			sort.Slice(termOptions, func(i, j int) bool {
+				return termOptions[i].OfferingClass+termOptions[i].PaymentOption < termOptions[j].OfferingClass+termOptions[j].PaymentOption
+			})
+
+			for first := 0; first < forecastMonths; first += term.Months {
+				plan := planReservations(fleet[key][first:first+term.Months], today, onDemandCosts[key][term.Months], termOptions)
+				plan.InstanceType = key.InstanceType
+				plan.Engine = key.Engine
+				plan.Term = term.Name
+				plan.FirstMonth = first + 1
+				plan.LastMonth = first + term.Months
+				plans = append(plans, plan)
+			}
+		}
+	}
+	return plans, nil
+}
+
+// forecastPrices returns the on-demand monthly cost of each instance type and engine
+// by the months of the term, which may differ between the terms with calendar hours,
+// and their reserved options.
+func forecastPrices(data1Year, data3Years []PricingData) (map[forecastKey]map[int]float64, map[forecastKey]map[string]forecastOption) {
+	onDemandCosts := make(map[forecastKey]map[int]float64)
+	options := make(map[forecastKey]map[string]forecastOption)
+	for _, term := range []struct {
+		Data   []PricingData
+		Months int
+	}{{data1Year, 12}, {data3Years, 36}} {
+		for _, row := range term.Data {
+			key := forecastKey{row.InstanceType, row.Engine}
+			if row.Term == "On-Demand" {
+				if onDemandCosts[key] == nil {
+					onDemandCosts[key] = make(map[int]float64)
+				}
+				onDemandCosts[key][term.Months] = row.MonthlyCostPerInstance
+				continue
+			}
+			if options[key] == nil {
+				options[key] = make(map[string]forecastOption)
+			}
+			// The pricing rows may be duplicated, keep one per option
+			options[key][fmt.Sprintf("%s-%s-%s", row.Term, row.OfferingClass, row.PaymentOption)] = forecastOption{
+				OfferingClass:        row.OfferingClass,
+				PaymentOption:        row.PaymentOption,
+				Months:               term.Months,
+				CostForTerm:          row.CostForTermPerInstance,
+				AmortizedMonthlyCost: row.AmortizedMonthlyCostPerInstance,
+			}
+		}
+	}
+	return onDemandCosts, options
+}
+
+// planReservations returns the reserved option and quantity with the lowest cost for
+// the monthly instance counts of a term, instances above the quantity running on-demand.
+func planReservations(counts []int, today int, onDemandCost float64, options []forecastOption) ForecastPlan {
+	plan := ForecastPlan{
+		InstancesToday:   today,
+		OnDemandMonthly:  onDemandCost,
+		MinInstances:     counts[0],
+		MonthlyInstances: counts,
+	}
+	for _, count := range counts {
+		plan.MinInstances = min(plan.MinInstances, count)
+		plan.MaxInstances = max(plan.MaxInstances, count)
+		plan.OnDemandCost += float64(count) * onDemandCost
+	}
+
+	termCost := func(option forecastOption, quantity int) float64 {
+		cost := float64(quantity) * option.CostForTerm
+		for _, count := range counts {
+			cost += float64(max(count-quantity, 0)) * onDemandCost
+		}
+		return cost
+	}
+
+	plan.RecommendedCost = plan.OnDemandCost
+	plan.CurrentCountCost = plan.OnDemandCost
+	var best forecastOption
+	for i, option := range options {
+		for quantity := 1; quantity <= plan.MaxInstances; quantity++ {
+			if cost := termCost(option, quantity); cost < plan.RecommendedCost {
+				plan.RecommendedCost, plan.Quantity, best = cost, quantity, option
+			}
+		}
+		if cost := termCost(option, today); i == 0 || cost < plan.CurrentCountCost {
+			plan.CurrentCountCost = cost
+		}
+	}
+	plan.OfferingClass = best.OfferingClass
+	plan.PaymentOption = best.PaymentOption
+
+	for _, count := range counts {
+		plan.MonthlyCosts = append(plan.MonthlyCosts,
+			float64(plan.Quantity)*best.AmortizedMonthlyCost+float64(max(count-plan.Quantity, 0))*onDemandCost)
+	}
+
+	plan.Savings = plan.OnDemandCost - plan.RecommendedCost
+	if plan.OnDemandCost != 0 {
+		plan.SavingsPercent = plan.Savings / plan.OnDemandCost * 100
+	}
+	return plan
+}
+
+// PrintForecast prints the recommended reservations of each term and the monthly
+// costs of the forecasted fleet under each plan.
+func PrintForecast(plans []ForecastPlan) {
+	fmt.Println("\n## Forecast Reservations")
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader(currencyHeaders([]string{
+		"Instance Type",
+		"Engine",
+		"Term",
+		"Months",
+		"Instances Today",
+		"Min Instances",
+		"Max Instances",
+		"Recommended Quantity",
+		"Offering Class",
+		"Payment Option",
+		"On-Demand Cost ($)",
+		"Cost Reserving Today's Count ($)",
+		"Recommended Cost ($)",
+		"Savings ($)",
+		"Savings (%)",
+	}))
+	table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	table.SetCenterSeparator("|")
+
+	for _, plan := range plans {
+		table.Append([]string{
+			plan.InstanceType,
+			plan.Engine,
+			plan.Term,
+			fmt.Sprintf("%d-%d", plan.FirstMonth, plan.LastMonth),
+			fmt.Sprintf("%d", plan.InstancesToday),
+			fmt.Sprintf("%d", plan.MinInstances),
+			fmt.Sprintf("%d", plan.MaxInstances),
+			fmt.Sprintf("%d", plan.Quantity),
+			valueOrNA(plan.OfferingClass, plan.OfferingClass),
+			valueOrNA(plan.PaymentOption, plan.PaymentOption),
+			formatCost(plan.OnDemandCost),
+			formatCost(plan.CurrentCountCost),
+			formatCost(plan.RecommendedCost),
+			formatCost(plan.Savings),
+			fmt.Sprintf("%.2f", plan.SavingsPercent),
+		})
+	}
+	table.Render()
+
+	fmt.Println("\n## Forecast Monthly Costs")
+	table = tablewriter.NewWriter(os.Stdout)
+	table.SetHeader(currencyHeaders([]string{
+		"Month",
+		"Instance Type",
+		"Engine",
+		"Instances",
+		"On-Demand Cost ($)",
+		"1 Year Plan Cost ($)",
+		"3 Year Plan Cost ($)",
+	}))
+	table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	table.SetCenterSeparator("|")
+
+	type monthlyRow struct {
+		Instances      int
+		OnDemandCost   float64
+		OneYearCost    float64
+		ThreeYearsCost float64
+	}
+	rows := make(map[forecastKey][]monthlyRow)
+	var keys []forecastKey
+	for _, plan := range plans {
+		key := forecastKey{plan.InstanceType, plan.Engine}
+		if _, ok := rows[key]; !ok {
+			rows[key] = make([]monthlyRow, forecastMonths)
+			keys = append(keys, key)
+		}
+		for i, cost := range plan.MonthlyCosts {
+			row := &rows[key][plan.FirstMonth-1+i]
+			row.Instances = plan.MonthlyInstances[i]
+			row.OnDemandCost = float64(row.Instances) * plan.OnDemandMonthly
+			if plan.Term == "1 Year" {
+				row.OneYearCost = cost
+			} else {
+				row.ThreeYearsCost = cost
+			}
+		}
+	}
+
+	for month := 0; month < forecastMonths; month++ {
+		for _, key := range keys {
+			row := rows[key][month]
+			table.Append([]string{
+				fmt.Sprintf("%d", month+1),
+				key.InstanceType,
+				key.Engine,
+				fmt.Sprintf("%d", row.Instances),
+				formatCost(row.OnDemandCost),
+				formatCost(row.OneYearCost),
+				formatCost(row.ThreeYearsCost),
+			})
+		}
+	}
+	table.Render()
+}
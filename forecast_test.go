@@ -0,0 +1,114 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestForecastFleet(t *testing.T) {
+	instances := []InstanceInfo{{InstanceType: "db.m5.large", Engine: "PostgreSQL", NumberOfInstances: 2}}
+	scenario := &ForecastScenario{Changes: []ForecastChange{
+		{Month: 7, InstanceType: "db.m5.large", Engine: "PostgreSQL", Count: 2},
+		{Month: 13, InstanceType: "db.m5.large", Engine: "PostgreSQL", Count: -3},
+		{Month: 25, InstanceType: "db.r6g.large", Engine: "MySQL", Count: 1},
+	}}
+
+	fleet, err := forecastFleet(instances, scenario)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		key   forecastKey
+		month int
+		want  int
+	}{
+		{forecastKey{"db.m5.large", "PostgreSQL"}, 1, 2},
+		{forecastKey{"db.m5.large", "PostgreSQL"}, 6, 2},
+		{forecastKey{"db.m5.large", "PostgreSQL"}, 7, 4},
+		{forecastKey{"db.m5.large", "PostgreSQL"}, 13, 1},
+		{forecastKey{"db.m5.large", "PostgreSQL"}, 36, 1},
+		{forecastKey{"db.r6g.large", "MySQL"}, 24, 0},
+		{forecastKey{"db.r6g.large", "MySQL"}, 25, 1},
+	} {
+		if got := fleet[test.key][test.month-1]; got != test.want {
+			t.Errorf("%s %s month %d: got %d instances, want %d", test.key.Engine, test.key.InstanceType, test.month, got, test.want)
+		}
+	}
+
+	scenario.Changes = append(scenario.Changes, ForecastChange{Month: 30, InstanceType: "db.m5.large", Engine: "PostgreSQL", Count: -2})
+	if _, err := forecastFleet(instances, scenario); err == nil {
+		t.Error("a fleet dropping below zero instances was accepted")
+	}
+}
+
+func TestPlanReservationsQuantity(t *testing.T) {
+	// Two instances all year and two more for the last 6 months, a reservation
+	// paying off above 8.4 months of use
+	counts := []int{2, 2, 2, 2, 2, 2, 4, 4, 4, 4, 4, 4}
+	options := []forecastOption{
+		{OfferingClass: "Standard", PaymentOption: "No Upfront", Months: 12, CostForTerm: 840, AmortizedMonthlyCost: 70},
+		{OfferingClass: "Standard", PaymentOption: "All Upfront", Months: 12, CostForTerm: 900, AmortizedMonthlyCost: 75},
+	}
+
+	plan := planReservations(counts, 4, 100, options)
+	if plan.MinInstances != 2 || plan.MaxInstances != 4 {
+		t.Errorf("got %d to %d instances, want 2 to 4", plan.MinInstances, plan.MaxInstances)
+	}
+	if plan.Quantity != 2 || plan.PaymentOption != "No Upfront" {
+		t.Errorf("got %d %s reservations, want 2 No Upfront", plan.Quantity, plan.PaymentOption)
+	}
+	for _, cost := range []struct {
+		name      string
+		got, want float64
+	}{
+		{"on-demand cost", plan.OnDemandCost, 3600},
+		{"recommended cost", plan.RecommendedCost, 2880},
+		{"cost reserving today's count", plan.CurrentCountCost, 3360},
+		{"savings", plan.Savings, 720},
+		{"savings percent", plan.SavingsPercent, 20},
+		{"first month cost", plan.MonthlyCosts[0], 140},
+		{"last month cost", plan.MonthlyCosts[11], 340},
+	} {
+		if math.Abs(cost.got-cost.want) > 1e-9 {
+			t.Errorf("got a %s of %.2f, want %.2f", cost.name, cost.got, cost.want)
+		}
+	}
+
+	// Reserving nothing when no option beats on-demand
+	plan = planReservations(counts, 4, 60, options)
+	if plan.Quantity != 0 || plan.RecommendedCost != plan.OnDemandCost || plan.Savings != 0 {
+		t.Errorf("got %d reservations costing %.2f, want none", plan.Quantity, plan.RecommendedCost)
+	}
+}
+
+func TestForecastPricesKeepsTheOnDemandCostOfEachTerm(t *testing.T) {
+	row := func(term string, monthly, costForTerm float64) PricingData {
+		return PricingData{
+			InstanceType:                    "db.m5.large",
+			Engine:                          "PostgreSQL",
+			Term:                            term,
+			OfferingClass:                   "Standard",
+			PaymentOption:                   "No Upfront",
+			MonthlyCostPerInstance:          monthly,
+			AmortizedMonthlyCostPerInstance: monthly,
+			CostForTermPerInstance:          costForTerm,
+		}
+	}
+	// Calendar hours give the terms different monthly on-demand costs
+	data1Year := []PricingData{row("On-Demand", 130.15, 1561.8), row("1 Year", 90, 1080), row("1 Year", 90, 1080)}
+	data3Years := []PricingData{row("On-Demand", 130.06, 4682.16), row("3 Year", 60, 2160)}
+
+	onDemandCosts, options := forecastPrices(data1Year, data3Years)
+	key := forecastKey{"db.m5.large", "PostgreSQL"}
+	if onDemandCosts[key][12] != 130.15 || onDemandCosts[key][36] != 130.06 {
+		t.Errorf("got on-demand costs %v, want 130.15 for 12 months and 130.06 for 36", onDemandCosts[key])
+	}
+	if len(options[key]) != 2 {
+		t.Fatalf("got options %v, want one per term", options[key])
+	}
+	for _, option := range options[key] {
+		if (option.Months == 12 && option.CostForTerm != 1080) || (option.Months == 36 && option.CostForTerm != 2160) {
+			t.Errorf("unexpected option %+v", option)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	ec2instancesinfo "github.com/LeanerCloud/ec2-instances-info"
+)
+
+// Current generation x86 families replacing the previous generation ones, by
+// instance class letter.
+var currentGenerationFamilies = map[string][]string{
+	"m": {"db.m6i", "db.m5"},
+	"r": {"db.r6i", "db.r5"},
+	"t": {"db.t3"},
+}
+
+// ComparePreviousGeneration prices the current generation replacement of each
+// previous generation instance type and compares it with the current costs.
+func ComparePreviousGeneration(region string, instances []InstanceInfo, data1Year, data3Years []PricingData) ([]MigrationComparison, error) {
+	return CompareMigration(region, instances, data1Year, data3Years, func(rdsData ec2instancesinfo.RDSInstanceData, current ec2instancesinfo.RDSInstance, engine string) string {
+		if current.CurrentGeneration {
+			return ""
+		}
+		return findEquivalentInstanceType(rdsData, region, engine, current, currentGenerationFamilies[instanceClassLetter(current.InstanceType)])
+	})
+}
+
+// PreviousGenerationInstances returns the instances whose instance type is a
+// previous generation one, whether or not a replacement can be priced.
+func PreviousGenerationInstances(instances []InstanceInfo) ([]InstanceInfo, error) {
+	rdsData, err := ec2instancesinfo.RDSData()
+	if err != nil {
+		errorLog.Printf("Error fetching RDS data: %v", err)
+		return nil, err
+	}
+
+	var previous []InstanceInfo
+	for _, instance := range instances {
+		if current := findRDSInstanceType(*rdsData, instance.InstanceType); current != nil && !current.CurrentGeneration {
+			previous = append(previous, instance)
+		}
+	}
+	sort.Slice(previous, func(i, j int) bool {
+		if previous[i].InstanceType != previous[j].InstanceType {
+			return previous[i].InstanceType < previous[j].InstanceType
+		}
+		return previous[i].Engine < previous[j].Engine
+	})
+	return previous, nil
+}
+
+// PrintPreviousGenerationWarnings warns about reserving previous generation
+// instance types and prices their replacement, when one was found.
+func PrintPreviousGenerationWarnings(previous []InstanceInfo, comparisons []MigrationComparison) {
+	targets := make(map[string]string)
+	for _, comparison := range comparisons {
+		targets[fmt.Sprintf("%s-%s", comparison.InstanceType, comparison.Engine)] = comparison.TargetInstanceType
+	}
+
+	warned := make(map[string]bool)
+	for _, instance := range previous {
+		key := fmt.Sprintf("%s-%s", instance.InstanceType, instance.Engine)
+		if warned[key] {
+			continue
+		}
+		warned[key] = true
+
+		advice := "No current generation replacement could be priced, check the available instance types before reserving."
+		if target, ok := targets[key]; ok {
+			advice = fmt.Sprintf("Consider upgrading to %s before reserving.", target)
+		}
+		fmt.Printf("\nWARNING: %s (%s) is a previous generation instance type, reserving it locks you into it for the whole term. %s\n",
+			instance.InstanceType, instance.Engine, advice)
+	}
+
+	PrintMigrationComparison(comparisons, "Previous Generation Upgrade Savings", "Replacement", "Upgrade + Reservation")
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"strings"
+
+	ec2instancesinfo "github.com/LeanerCloud/ec2-instances-info"
+)
+
+// Graviton families to migrate to, by instance class letter.
+var gravitonFamilies = map[string][]string{
+	"m": {"db.m7g", "db.m6g"},
+	"r": {"db.r7g", "db.r6g"},
+	"t": {"db.t4g"},
+	"x": {"db.x2g"},
+}
+
+func isGraviton(instance ec2instancesinfo.RDSInstance) bool {
+	return strings.Contains(instance.PhysicalProcessor, "Graviton")
+}
+
+// instanceClassLetter returns the class letter of an instance type, such as m for db.m5.large.
+func instanceClassLetter(instanceType string) string {
+	name := strings.TrimPrefix(instanceType, "db.")
+	if name == "" {
+		return ""
+	}
+	return name[:1]
+}
+
+// findEquivalentInstanceType returns the cheapest class of the given families with the
+// same vCPUs and memory, falling back to the cheapest class having at least as many
+// vCPUs and as much memory. Only classes priced for the engine in the region are considered.
+func findEquivalentInstanceType(rdsData ec2instancesinfo.RDSInstanceData, region, engine string, current ec2instancesinfo.RDSInstance, families []string) string {
+	inFamilies := make(map[string]bool)
+	for _, family := range families {
+		inFamilies[family] = true
+	}
+
+	bestType, bestPrice, bestIsExact := "", 0.0, false
+	for _, candidate := range rdsData {
+		if !inFamilies[rdsInstanceFamily(candidate.InstanceType)] {
+			continue
+		}
+		price := rdsEnginePricing(candidate.Pricing[region], engine).OnDemand
+		if price == 0 || candidate.Vcpu < current.Vcpu || candidate.Memory < current.Memory {
+			continue
+		}
+
+		exact := candidate.Vcpu == current.Vcpu && candidate.Memory == current.Memory
+		if bestType == "" || (exact && !bestIsExact) || (exact == bestIsExact && price < bestPrice) {
+			bestType, bestPrice, bestIsExact = candidate.InstanceType, price, exact
+		}
+	}
+	return bestType
+}
+
+// CompareGraviton prices the Graviton equivalent of each x86 instance type of the
+// aggregated instances and compares it with the current costs for both terms.
+func CompareGraviton(region string, instances []InstanceInfo, data1Year, data3Years []PricingData) ([]MigrationComparison, error) {
+	return CompareMigration(region, instances, data1Year, data3Years, func(rdsData ec2instancesinfo.RDSInstanceData, current ec2instancesinfo.RDSInstance, engine string) string {
+		if isGraviton(current) {
+			return ""
+		}
+		return findEquivalentInstanceType(rdsData, region, engine, current, gravitonFamilies[instanceClassLetter(current.InstanceType)])
+	})
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// Conventions for the number of hours in a month.
+const (
+	HoursConventionAverage  = "730"      // The 730 hours AWS uses for monthly prices
+	HoursConventionYear     = "8760/12"  // Alias of 730, stated as 365 days years split in 12 months
+	HoursConventionCalendar = "calendar" // The actual hours of the term starting at the term start date
+)
+
+// Hours of the reservation terms, as used by AWS to amortize the upfront fees.
+const hoursPerYear = 365 * 24
+
+// ParseHoursConvention validates the hours convention, defaulting to the calendar
+// hours when a term start date is given and to 730 hours otherwise.
+func ParseHoursConvention(convention, termStart string) (string, time.Time, error) {
+	start := time.Now().UTC().Truncate(24 * time.Hour)
+	if termStart != "" {
+		var err error
+		if start, err = time.Parse("2006-01-02", termStart); err != nil {
+			return "", time.Time{}, fmt.Errorf("invalid term start %q, expected YYYY-MM-DD", termStart)
+		}
+	}
+
+	switch convention {
+	case "":
+		if termStart != "" {
+			return HoursConventionCalendar, start, nil
+		}
+		return HoursConventionAverage, start, nil
+	case HoursConventionAverage, HoursConventionYear, HoursConventionCalendar:
+		if termStart != "" && convention != HoursConventionCalendar {
+			return "", time.Time{}, fmt.Errorf("a term start date only applies to the %s convention", HoursConventionCalendar)
+		}
+		if convention == HoursConventionYear {
+			return HoursConventionAverage, start, nil
+		}
+		return convention, start, nil
+	}
+	return "", time.Time{}, fmt.Errorf("unknown hours convention %q, expected %s, %s or %s",
+		convention, HoursConventionAverage, HoursConventionYear, HoursConventionCalendar)
+}
+
+// termHours returns the number of hours of a term of the given years.
+func termHours(years int) float64 {
+	if HoursConvention == HoursConventionCalendar {
+		return TermStart.AddDate(years, 0, 0).Sub(TermStart).Hours()
+	}
+	return float64(HoursInMonth * 12 * years)
+}
+
+// monthlyHours returns the average number of hours in a month of a term of the given years.
+func monthlyHours(years int) float64 {
+	return termHours(years) / float64(12*years)
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/olekukonko/tablewriter"
+)
+
+// availabilityZones describes where the instance runs, including the standby of Multi-AZ instances.
+func availabilityZones(primary, secondary *string) string {
+	if aws.ToString(secondary) == "" {
+		return aws.ToString(primary)
+	}
+	return fmt.Sprintf("%s, %s", aws.ToString(primary), aws.ToString(secondary))
+}
+
+func deploymentOption(multiAZ bool) string {
+	if multiAZ {
+		return "Multi-AZ"
+	}
+	return "Single-AZ"
+}
+
+func formatTags(tags map[string]string) string {
+	pairs := make([]string, 0, len(tags))
+	for key, value := range tags {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", key, value))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ", ")
+}
+
+// PrintInventory lists each database, EC2 instance or cache cluster included in the
+// pricing tables.
+func PrintInventory(instances []InstanceInfo) {
+	sorted := make([]InstanceInfo, len(instances))
+	copy(sorted, instances)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Identifier < sorted[j].Identifier
+	})
+
+	fmt.Println("\n## Inventory")
+	table := tablewriter.NewWriter(os.Stdout)
+	if Service == ServiceEC2 {
+		table.SetHeader([]string{
+			"Identifier",
+			"ARN",
+			"Instance Type",
+			"Platform",
+			"Tenancy",
+			"Availability Zone",
+			"Launched",
+			"Tags",
+		})
+	} else if Service == ServiceElastiCache || Service == ServiceRedshift || Service == ServiceMemoryDB {
+		table.SetHeader([]string{
+			"Identifier",
+			"ARN",
+			"Node Type",
+			"Nodes",
+			"Engine",
+			"Engine Version",
+			"Deployment",
+			"Availability Zone",
+			"Created",
+			"Tags",
+		})
+	} else {
+		table.SetHeader([]string{
+			"Identifier",
+			"ARN",
+			"Instance Type",
+			"Engine",
+			"Engine Version",
+			"Deployment",
+			"Availability Zone",
+			"Created",
+			"Tags",
+		})
+	}
+	table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	table.SetCenterSeparator("|")
+
+	for _, instance := range sorted {
+		created := ""
+		if !instance.CreationTime.IsZero() {
+			created = instance.CreationTime.UTC().Format(time.RFC3339)
+		}
+		if Service == ServiceEC2 {
+			table.Append([]string{
+				instance.Identifier,
+				instance.ARN,
+				instance.InstanceType,
+				ec2EnginePlatform(instance.Engine),
+				instance.Tenancy,
+				instance.AvailabilityZone,
+				created,
+				formatTags(instance.Tags),
+			})
+			continue
+		}
+		if Service == ServiceElastiCache || Service == ServiceRedshift || Service == ServiceMemoryDB {
+			table.Append([]string{
+				instance.Identifier,
+				instance.ARN,
+				instance.InstanceType,
+				fmt.Sprintf("%d", instance.NumberOfInstances),
+				instance.Engine,
+				instance.EngineVersion,
+				deploymentOption(instance.MultiAZ),
+				instance.AvailabilityZone,
+				created,
+				formatTags(instance.Tags),
+			})
+			continue
+		}
+		table.Append([]string{
+			instance.Identifier,
+			instance.ARN,
+			instance.InstanceType,
+			instance.Engine,
+			instance.EngineVersion,
+			deploymentOption(instance.MultiAZ),
+			instance.AvailabilityZone,
+			created,
+			formatTags(instance.Tags),
+		})
+	}
+
+	table.Render()
+}
@@ -9,8 +9,10 @@ import (
 
 	"os"
 	"strings"
+	"time"
 
 	ec2instancesinfo "github.com/LeanerCloud/ec2-instances-info"
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/rds"
 	"github.com/olekukonko/tablewriter"
@@ -39,6 +41,16 @@ type InstanceInfo struct {
 	InstanceType      string
 	NumberOfInstances int
 	Engine            string
+	MultiAZ           bool
+	Tags              map[string]string
+	Status            string
+	Identifier        string
+	ARN               string
+	EngineVersion     string
+	AvailabilityZone  string
+	CreationTime      time.Time
+	Identifiers       []string // Databases aggregated into this entry
+	Tenancy           string   // Tenancy of EC2 instances
 }
 
 type PricingData struct {
@@ -47,6 +59,7 @@ type PricingData struct {
 	InstanceType                    string
 	MonthlyCostPerInstance          float64
 	NumberOfInstances               int
+	OfferingClass                   string
 	PaymentOption                   string
 	Region                          string
 	Savings                         float64
@@ -58,6 +71,13 @@ type PricingData struct {
 	CostForTermPerInstance          float64
 	TotalMonthlyCost                float64
 	UpfrontCost                     float64
+	Identifiers                     []string
+	SuggestedInstanceType           string
+	SuggestedTotalCostForTerm       float64
+	RightsizingSavings              float64
+	DiscountPercent                 float64 // Discount on the list prices included in the costs
+	OfferingStatus                  string  // Whether AWS offers the reserved option, once validated
+	Tenancy                         string  // Tenancy of EC2 instances
 }
 
 type InstancePricing struct {
@@ -66,7 +86,30 @@ type InstancePricing struct {
 }
 
 var (
-	Region string
+	Region                   string
+	Service                  string
+	TerraformFile            string
+	CloudFormationFile       string
+	CloudFormationParameters map[string]string
+	IncludedStatuses         []string
+	FilterTags               map[string]string
+	GroupByTag               string
+	UptimeDays               int
+	MinUptimePercent         float64
+	RightsizingDays          int
+	CompareGravitonFlag      bool
+	CompareSavingsPlansFlag  bool
+	CommitmentDiscounts      map[string]float64
+	CommitmentRegions        []string
+	StatusPolicy             string
+	ForecastFile             string
+	CoverageDays             int
+	HoursConvention          string
+	TermStart                time.Time
+	DiscountsFile            string
+	PlanOutFile              string
+	PlanTerm                 string
+	ValidateOfferings        bool
 )
 
 type logWriter struct {
@@ -93,18 +136,8 @@ func FetchAndProcessRDSData(region string, instances []InstanceInfo) ([]ec2insta
 	for _, instance := range instances {
 
 		for _, data := range *rdsData {
-			if pricing, ok := data.Pricing[region]; ok {
-				switch instance.Engine {
-				case "MySQL":
-					if pricing.MySQL.OnDemand != 0 {
-						filteredInstances = append(filteredInstances, data)
-					}
-				case "PostgreSQL":
-					if pricing.PostgreSQL.OnDemand != 0 {
-						filteredInstances = append(filteredInstances, data)
-					}
-					// Add cases for other database engines as needed
-				}
+			if pricing, ok := data.Pricing[region]; ok && rdsEnginePricing(pricing, instance.Engine).OnDemand != 0 {
+				filteredInstances = append(filteredInstances, data)
 			}
 		}
 	}
@@ -117,35 +150,37 @@ func ProcessOnDemand(instance ec2instancesinfo.RDSInstance, region string, servi
 	var onDemandPrice float64
 
 	// Extract on-demand pricing based on service
-	switch service {
-	case "MySQL":
-		onDemandPrice = instance.Pricing[region].MySQL.OnDemand
-	case "PostgreSQL":
-		onDemandPrice = instance.Pricing[region].PostgreSQL.OnDemand
-		// Add cases for other database engines as needed
-	}
+	onDemandPrice = rdsEnginePricing(instance.Pricing[region], service).OnDemand
+
+	onDemandPrice *= discountFactor("RDS", region)
 
 	// Debug: Ensure that the onDemandPrice is correctly fetched
 	debugLog.Printf("On-Demand Price for %s in region %s: %f", service, region, onDemandPrice)
 
+	return onDemandRows(instance.InstanceType, region, onDemandPrice, numberOfInstances)
+}
+
+// onDemandRows returns the on-demand rows of an instance type for both terms, from
+// its effective on-demand hourly price.
+func onDemandRows(instanceType, region string, onDemandPrice float64, numberOfInstances int) (PricingData, PricingData) {
 	// Calculate monthly cost considering the number of instances
-	monthlyCost := onDemandPrice * float64(HoursInMonth)
-	debugLog.Printf("Monthly Cost for %s in region %s: %f", service, region, monthlyCost)
+	monthlyCost := onDemandPrice * monthlyHours(1)
+	debugLog.Printf("Monthly Cost for %s in region %s: %f", instanceType, region, monthlyCost)
 
 	// Calculate costs for 1-year and 3-year terms
-	totalCostForTerm1Year := monthlyCost * 12
-	totalCostForTerm3Years := monthlyCost * 36
+	totalCostForTerm1Year := onDemandPrice * termHours(1)
+	totalCostForTerm3Years := onDemandPrice * termHours(3)
 
 	// Create PricingData structs for 1-year and 3-year terms
 	data1Year := PricingData{
 		Region:                          region,
-		InstanceType:                    instance.InstanceType,
+		InstanceType:                    instanceType,
 		AmortizedMonthlyCostPerInstance: monthlyCost,
 		NumberOfInstances:               numberOfInstances,
 		Term:                            "On-Demand",
 		PaymentOption:                   "N/A",
 		UpfrontCost:                     0,
-		MonthlyCostPerInstance:          onDemandPrice * float64(HoursInMonth),
+		MonthlyCostPerInstance:          monthlyCost,
 		TotalCostForTerm:                totalCostForTerm1Year * float64(numberOfInstances),
 		CostForTermPerInstance:          totalCostForTerm1Year,
 		Savings:                         0,
@@ -157,18 +192,25 @@ func ProcessOnDemand(instance ec2instancesinfo.RDSInstance, region string, servi
 
 	data3Years := data1Year
 
+	// A calendar 3-year term may have a different share of leap days
+	monthlyCost3Years := onDemandPrice * monthlyHours(3)
+	data3Years.AmortizedMonthlyCostPerInstance = monthlyCost3Years
+	data3Years.MonthlyCostPerInstance = monthlyCost3Years
+	data3Years.TotalMonthlyCost = monthlyCost3Years * float64(numberOfInstances)
+	data3Years.TotalAmortizedMonthlyCost = monthlyCost3Years * float64(numberOfInstances)
 	data3Years.CostForTermPerInstance = totalCostForTerm3Years
 	data3Years.TotalCostForTerm = totalCostForTerm3Years * float64(numberOfInstances)
 
 	return data1Year, data3Years
 }
 
-// GetRunningRdsInstances fetches running RDS instances
-func GetRunningRdsInstances(region string) ([]InstanceInfo, error) {
+// GetRunningRdsInstances fetches the RDS instances whose status is one of the
+// given statuses, the others are returned as excluded instances.
+func GetRunningRdsInstances(region string, statuses []string) ([]InstanceInfo, []ExcludedInstance, error) {
 	cfg, err := config.LoadDefaultConfig(context.TODO(), config.WithRegion(region))
 	if err != nil {
 		errorLog.Printf("Error loading AWS config: %v", err)
-		return nil, err
+		return nil, nil, err
 	}
 
 	svc := rds.NewFromConfig(cfg)
@@ -177,22 +219,59 @@ func GetRunningRdsInstances(region string) ([]InstanceInfo, error) {
 	result, err := svc.DescribeDBInstances(context.TODO(), input)
 	if err != nil {
 		errorLog.Printf("Error describing RDS instances: %v", err)
-		return nil, err
+		return nil, nil, err
 	}
 
 	var instances []InstanceInfo
+	var excluded []ExcludedInstance
 	for _, dbInstance := range result.DBInstances {
-		if *dbInstance.DBInstanceStatus == "available" {
-			instances = append(instances, InstanceInfo{
-				InstanceType:      *dbInstance.DBInstanceClass,
-				NumberOfInstances: 1,
-				Engine:            determineServiceFromDBEngine(dbInstance.Engine),
+		status := aws.ToString(dbInstance.DBInstanceStatus)
+		if !isStatusIncluded(status, statuses) {
+			excluded = append(excluded, ExcludedInstance{
+				Identifier:   aws.ToString(dbInstance.DBInstanceIdentifier),
+				InstanceType: aws.ToString(dbInstance.DBInstanceClass),
+				Engine:       rdsEngine(aws.ToString(dbInstance.Engine), aws.ToString(dbInstance.LicenseModel)),
+				Status:       status,
+				Reason:       "Status not included by the status policy",
 			})
+			continue
 		}
+
+		engine := rdsEngine(aws.ToString(dbInstance.Engine), aws.ToString(dbInstance.LicenseModel))
+		multiAZ := aws.ToBool(dbInstance.MultiAZ) && !isAuroraEngine(engine)
+		instances = append(instances, InstanceInfo{
+			InstanceType:      *dbInstance.DBInstanceClass,
+			NumberOfInstances: instanceCountForDeployment(1, multiAZ),
+			Engine:            engine,
+			MultiAZ:           multiAZ,
+			Tags:              tagsFromRDSTagList(dbInstance.TagList),
+			Status:            status,
+			Identifier:        aws.ToString(dbInstance.DBInstanceIdentifier),
+			ARN:               aws.ToString(dbInstance.DBInstanceArn),
+			EngineVersion:     aws.ToString(dbInstance.EngineVersion),
+			AvailabilityZone:  availabilityZones(dbInstance.AvailabilityZone, dbInstance.SecondaryAvailabilityZone),
+			CreationTime:      aws.ToTime(dbInstance.InstanceCreateTime),
+		})
 	}
 
 	debugLog.Printf("Found running instances: %v", instances)
-	return instances, nil
+	debugLog.Printf("Excluded instances: %v", excluded)
+	return instances, excluded, nil
+}
+
+// instanceCountForDeployment returns the number of billable instances, Multi-AZ
+// deployments being charged (and reserved) as two instances.
+func instanceCountForDeployment(count int, multiAZ bool) int {
+	if multiAZ {
+		return count * 2
+	}
+	return count
+}
+
+// isAuroraEngine reports whether the engine is an Aurora one, whose replicas are
+// separate instances rather than Multi-AZ standbys.
+func isAuroraEngine(engine string) bool {
+	return strings.HasPrefix(engine, "Aurora ")
 }
 
 func determineServiceFromDBEngine(engine *string) string {
@@ -202,35 +281,122 @@ func determineServiceFromDBEngine(engine *string) string {
 		return "MySQL"
 	case "postgres":
 		return "PostgreSQL"
+	case "aurora-mysql", "aurora":
+		return "Aurora MySQL"
+	case "aurora-postgresql":
+		return "Aurora PostgreSQL"
+	case "mariadb":
+		return "MariaDB"
+	case "oracle-se2", "oracle-se2-cdb":
+		return EngineOracleSE2
+	case "oracle-ee", "oracle-ee-cdb":
+		return EngineOracleEEBYOL // Only sold with a customer provided license
+	case "sqlserver-ee":
+		return EngineSQLServerEE
+	case "sqlserver-se":
+		return EngineSQLServerSE
+	case "sqlserver-web":
+		return EngineSQLServerWeb
+	case "sqlserver-ex":
+		return EngineSQLServerExpress
+	case "docdb":
+		return EngineDocumentDB
+	case "neptune":
+		return EngineNeptune
 	// Add other cases as needed
 	default:
 		return "Unknown"
 	}
 }
 
-func ProcessReservedOption(instanceType, term string, amortizedHourlyCost float64, hoursInMonth int, onDemandHourly float64, numberOfInstances int) PricingData {
+// rdsEnginePricing returns the pricing of the given engine from the regional RDS prices.
+func rdsEnginePricing(prices ec2instancesinfo.RDSRegionPrices, engine string) ec2instancesinfo.RDSPricing {
+	switch engine {
+	case "MySQL":
+		return prices.MySQL
+	case "PostgreSQL":
+		return prices.PostgreSQL
+	case "Aurora MySQL":
+		return prices.AuroraMySQL
+	case "Aurora PostgreSQL":
+		return prices.AuroraPostgreSQL
+	case "MariaDB":
+		return prices.MariaDB
+		// The Oracle and SQL Server prices don't tell the editions and license
+		// models apart, those engines are priced from the AWS Price List API
+	}
+	return ec2instancesinfo.RDSPricing{}
+}
+
+// reservedOption is the amortized hourly list price of a reserved option, its term
+// naming the duration, offering class and payment option, e.g. yrTerm1Standard.noUpfront.
+type reservedOption struct {
+	Term  string
+	Price float64
+}
+
+// rdsReservedOptions lists the reserved options of the RDS pricing of an engine.
+func rdsReservedOptions(pricing ec2instancesinfo.RDSPricing) []reservedOption {
+	return []reservedOption{
+		{"yrTerm1Standard.noUpfront", pricing.Reserved.StandardNoUpfront1Year},
+		{"yrTerm3Standard.noUpfront", pricing.Reserved.StandardNoUpfront3Years},
+		{"yrTerm1Standard.partialUpfront", pricing.Reserved.StandardPartiallUpfront1Year},
+		{"yrTerm3Standard.partialUpfront", pricing.Reserved.StandardPartialUpfront3Years},
+		{"yrTerm1Standard.allUpfront", pricing.Reserved.StandardAllUpfront1Year},
+		{"yrTerm3Standard.allUpfront", pricing.Reserved.StandardAllUpfront3Years},
+		{"yrTerm1Convertible.noUpfront", pricing.Reserved.ConvertibleNoUpfront1Year},
+		{"yrTerm3Convertible.noUpfront", pricing.Reserved.ConvertibleNoUpfront3Years},
+		{"yrTerm1Convertible.partialUpfront", pricing.Reserved.ConvertiblePartiallUpfront1Year},
+		{"yrTerm3Convertible.partialUpfront", pricing.Reserved.ConvertiblePartialUpfront3Years},
+		{"yrTerm1Convertible.allUpfront", pricing.Reserved.ConvertibleAllUpfront1Year},
+		{"yrTerm3Convertible.allUpfront", pricing.Reserved.ConvertibleAllUpfront3Years},
+	}
+}
+
+// reservedRows prices the reserved options offered for an instance type, applying the
+// discount factor to the list prices, and splits the rows by term.
+func reservedRows(instanceType string, options []reservedOption, onDemandHourly, discount float64, numberOfInstances int) ([]PricingData, []PricingData) {
+	var data1Year, data3Years []PricingData
+	for _, option := range options {
+		if option.Price != 0 {
+			reservedRow := ProcessReservedOption(instanceType, option.Term, option.Price*discount, onDemandHourly*discount, numberOfInstances)
+			if strings.Contains(option.Term, "yrTerm1") {
+				data1Year = append(data1Year, reservedRow)
+			} else {
+				data3Years = append(data3Years, reservedRow)
+			}
+		}
+	}
+	return data1Year, data3Years
+}
+
+func ProcessReservedOption(instanceType, term string, amortizedHourlyCost float64, onDemandHourly float64, numberOfInstances int) PricingData {
 	termYears := 1
 	if strings.Contains(term, "yrTerm3") {
 		termYears = 3
 	}
 	monthsInTerm := 12 * termYears
 	paymentOption := strings.Split(term, ".")[1]
+	offeringClass := strings.Split(term, ".")[0][len("yrTerm1"):]
 
-	amortizedMonthlyCost := amortizedHourlyCost * float64(hoursInMonth)
-	upfrontCost, monthlyCost := 0.0, amortizedMonthlyCost
-
+	upfrontShare := 0.0
 	if paymentOption == "partialUpfront" {
-		upfrontCost = amortizedMonthlyCost * float64(monthsInTerm) * 0.5
-		monthlyCost = amortizedMonthlyCost * 0.5
+		upfrontShare = 0.5
 	} else if paymentOption == "allUpfront" {
-		upfrontCost = amortizedMonthlyCost * float64(monthsInTerm)
-		monthlyCost = 0
+		upfrontShare = 1
 	}
 
-	totaCostForTermPerInstance := amortizedMonthlyCost * float64(monthsInTerm)
+	// AWS charges a fixed upfront fee amortized over 8760 hours a year, only the
+	// recurring hourly charges depend on the hours of the term
+	upfrontCost := amortizedHourlyCost * upfrontShare * float64(hoursPerYear*termYears)
+	recurringHourly := amortizedHourlyCost * (1 - upfrontShare)
+	monthlyCost := recurringHourly * monthlyHours(termYears)
+
+	totaCostForTermPerInstance := upfrontCost + recurringHourly*termHours(termYears)
+	amortizedMonthlyCost := totaCostForTermPerInstance / float64(monthsInTerm)
 	totalCostForTerm := totaCostForTermPerInstance * float64(numberOfInstances) // calculate total cost for all instances
 
-	totalOnDemandCost := onDemandHourly * float64(HoursInMonth) * float64(monthsInTerm)
+	totalOnDemandCost := onDemandHourly * termHours(termYears)
 	savings := totalOnDemandCost - totaCostForTermPerInstance
 	savingsPercent := 0.0
 	if totalOnDemandCost != 0 {
@@ -244,6 +410,7 @@ func ProcessReservedOption(instanceType, term string, amortizedHourlyCost float6
 		AmortizedMonthlyCostPerInstance: amortizedMonthlyCost,
 		NumberOfInstances:               numberOfInstances,
 		Term:                            fmt.Sprintf("%d Year", termYears),
+		OfferingClass:                   offeringClass,
 		PaymentOption:                   paymentOption,
 		UpfrontCost:                     upfrontCost,
 		MonthlyCostPerInstance:          monthlyCost,
@@ -265,7 +432,7 @@ func PrintMarkdownTable(data []PricingData, columns []string, title string) {
 
 	header = append(header, columns...)
 
-	table.SetHeader(header)
+	table.SetHeader(currencyHeaders(header))
 	//table.SetAlignment()
 	table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
 	table.SetCenterSeparator("|")
@@ -286,39 +453,67 @@ func convertPricingDataToSlice(data PricingData, columns []string) []string {
 		case "Instance Type":
 			row = append(row, data.InstanceType)
 		case "Amortized Monthly Cost/instance ($)":
-			row = append(row, fmt.Sprintf("%.2f", data.AmortizedMonthlyCostPerInstance)) //
+			row = append(row, formatCost(data.AmortizedMonthlyCostPerInstance)) //
 		case "Number of Instances":
 			row = append(row, fmt.Sprintf("%d", data.NumberOfInstances))
 		case "Term":
 			row = append(row, data.Term)
 		case "Payment Option":
 			row = append(row, data.PaymentOption)
+		case "Offering Class":
+			row = append(row, valueOrNA(data.OfferingClass, data.OfferingClass))
 		case "Upfront Cost / instance ($)":
-			row = append(row, fmt.Sprintf("%.2f", data.UpfrontCost))
+			row = append(row, formatCost(data.UpfrontCost))
 		case "Monthly Cost / instance ($)":
-			row = append(row, fmt.Sprintf("%.2f", data.MonthlyCostPerInstance))
+			row = append(row, formatCost(data.MonthlyCostPerInstance))
 		case "Total Cost for Term / instance ($)":
-			row = append(row, fmt.Sprintf("%.2f", data.CostForTermPerInstance))
+			row = append(row, formatCost(data.CostForTermPerInstance))
 		case "Savings ($)":
-			row = append(row, fmt.Sprintf("%.2f", data.Savings))
+			row = append(row, formatCost(data.Savings))
 		case "Savings (%)":
 			row = append(row, fmt.Sprintf("%.2f", data.SavingsPercent))
 		case "Total Upfront Cost ($)":
-			row = append(row, fmt.Sprintf(" %.2f", data.TotalUpfrontCost))
+			row = append(row, " "+formatCost(data.TotalUpfrontCost))
 		case "Total Monthly Cost ($)":
-			row = append(row, fmt.Sprintf(" %.2f", data.TotalMonthlyCost))
+			row = append(row, " "+formatCost(data.TotalMonthlyCost))
 		case "Monthly Cost /instance ($)":
-			row = append(row, fmt.Sprintf("%.2f", data.MonthlyCostPerInstance)) //
+			row = append(row, formatCost(data.MonthlyCostPerInstance)) //
 		case "Total Amortized Monthly Cost ($)":
-			row = append(row, fmt.Sprintf("%.2f", data.TotalAmortizedMonthlyCost))
+			row = append(row, formatCost(data.TotalAmortizedMonthlyCost))
 		case "Total Cost for Term ($)":
-			row = append(row, fmt.Sprintf("%.2f", data.TotalCostForTerm))
+			row = append(row, formatCost(data.TotalCostForTerm))
+		case "Databases", "Instances", "Clusters", "Domains":
+			row = append(row, strings.Join(data.Identifiers, ", "))
+		case "Platform":
+			row = append(row, ec2EnginePlatform(data.Engine))
+		case "Tenancy":
+			row = append(row, data.Tenancy)
+		case "Suggested Instance Type":
+			row = append(row, valueOrNA(data.SuggestedInstanceType, data.SuggestedInstanceType))
+		case "Suggested Total Cost for Term ($)":
+			row = append(row, valueOrNA(data.SuggestedInstanceType, formatCost(data.SuggestedTotalCostForTerm)))
+		case "Rightsizing Savings ($)":
+			row = append(row, valueOrNA(data.SuggestedInstanceType, formatCost(data.RightsizingSavings)))
+		case "Discount (%)":
+			row = append(row, fmt.Sprintf("%.2f", data.DiscountPercent))
+		case "AWS Offering":
+			row = append(row, valueOrNA(data.OfferingStatus, data.OfferingStatus))
+		case "List Total Cost for Term ($)":
+			row = append(row, formatCost(listPrice(data.TotalCostForTerm, data.DiscountPercent)))
 			// Add other cases as needed based on your PricingData struct fields
 		}
 	}
 	return row
 }
 
+// valueOrNA returns N/A instead of the value when the field it depends on is empty.
+func valueOrNA(field, value string) string {
+	if field == "" {
+		return "N/A"
+	}
+	return value
+}
+
 // AggregateCosts aggregates costs based on instance counts and returns PricingData structs.
 func AggregateCosts(data []PricingData, instances []InstanceInfo) []PricingData {
 	instanceCounts := make(map[string]int)
@@ -352,6 +547,7 @@ func AggregateCostsByTermAndEngine(data []PricingData, instances []InstanceInfo,
 			count := getInstanceCount(d.InstanceType, engine, instances)
 			if count > 0 {
 				d.NumberOfInstances = count
+				d.Identifiers = getInstanceIdentifiers(d.InstanceType, engine, instances)
 				d.TotalMonthlyCost = d.MonthlyCostPerInstance * float64(count)
 				d.TotalAmortizedMonthlyCost = d.AmortizedMonthlyCostPerInstance * float64(count)
 				if d.Term != "On-Demand" {
@@ -406,45 +602,14 @@ func ProcessInstanceType(instance ec2instancesinfo.RDSInstance, region string, e
 
 	// Process reserved pricing if available
 	if servicePricing, ok := instance.Pricing[region]; ok {
-		var serviceRDSPricing ec2instancesinfo.RDSPricing
-		switch engine {
-		case "MySQL":
-			serviceRDSPricing = servicePricing.MySQL
-
-		case "PostgreSQL":
-			serviceRDSPricing = servicePricing.PostgreSQL
-			// Add cases for other services
-		}
-
-		// Manually process each reserved pricing option
-		reservedOptions := []struct {
-			Term  string
-			Price float64
-		}{
-			{"yrTerm1Standard.noUpfront", serviceRDSPricing.Reserved.StandardNoUpfront1Year},
-			{"yrTerm3Standard.noUpfront", serviceRDSPricing.Reserved.StandardNoUpfront3Years},
-			{"yrTerm1Standard.partialUpfront", serviceRDSPricing.Reserved.StandardPartiallUpfront1Year},
-			{"yrTerm3Standard.partialUpfront", serviceRDSPricing.Reserved.StandardPartialUpfront3Years},
-			{"yrTerm1Standard.allUpfront", serviceRDSPricing.Reserved.StandardAllUpfront1Year},
-			{"yrTerm3Standard.allUpfront", serviceRDSPricing.Reserved.StandardAllUpfront3Years},
-			{"yrTerm1Convertible.noUpfront", serviceRDSPricing.Reserved.ConvertibleNoUpfront1Year},
-			{"yrTerm3Convertible.noUpfront", serviceRDSPricing.Reserved.ConvertibleNoUpfront3Years},
-			{"yrTerm1Convertible.partialUpfront", serviceRDSPricing.Reserved.ConvertiblePartiallUpfront1Year},
-			{"yrTerm3Convertible.partialUpfront", serviceRDSPricing.Reserved.ConvertiblePartialUpfront3Years},
-			{"yrTerm1Convertible.allUpfront", serviceRDSPricing.Reserved.ConvertibleAllUpfront1Year},
-			{"yrTerm3Convertible.allUpfront", serviceRDSPricing.Reserved.ConvertibleAllUpfront3Years},
-		}
-
-		for _, option := range reservedOptions {
-			if option.Price != 0 {
-				reservedRow := ProcessReservedOption(instance.InstanceType, option.Term, option.Price, HoursInMonth, serviceRDSPricing.OnDemand, numberOfInstances)
-				if strings.Contains(option.Term, "yrTerm1") {
-					data1Year = append(data1Year, reservedRow)
-				} else {
-					data3Years = append(data3Years, reservedRow)
-				}
-			}
-		}
+		serviceRDSPricing := rdsEnginePricing(servicePricing, engine)
+
+		discount := discountFactor("RDS", region)
+
+		reservedOptions := rdsReservedOptions(serviceRDSPricing)
+		reserved1Year, reserved3Years := reservedRows(instance.InstanceType, reservedOptions, serviceRDSPricing.OnDemand, discount, numberOfInstances)
+		data1Year = append(data1Year, reserved1Year...)
+		data3Years = append(data3Years, reserved3Years...)
 
 		debugLog.Printf("Reserved pricing options: %v", reservedOptions)
 	} else {
@@ -453,9 +618,11 @@ func ProcessInstanceType(instance ec2instancesinfo.RDSInstance, region string, e
 
 	for i := range data1Year {
 		data1Year[i].Engine = engine
+		data1Year[i].DiscountPercent = Discounts.DiscountPercent("RDS", region)
 	}
 	for i := range data3Years {
 		data3Years[i].Engine = engine
+		data3Years[i].DiscountPercent = Discounts.DiscountPercent("RDS", region)
 	}
 
 	debugLog.Printf("Data 1 year: %v", data1Year)
@@ -511,16 +678,32 @@ func getInstanceCount(instanceType, engine string, instances []InstanceInfo) int
 	return count
 }
 
+// getInstanceIdentifiers returns the identifiers of the databases of a given type and engine.
+func getInstanceIdentifiers(instanceType, engine string, instances []InstanceInfo) []string {
+	var identifiers []string
+	for _, instance := range instances {
+		if instance.InstanceType == instanceType && instance.Engine == engine {
+			identifiers = append(identifiers, instance.Identifiers...)
+		}
+	}
+	return identifiers
+}
+
 func aggregateInstances(instances []InstanceInfo) []InstanceInfo {
 	aggregated := make(map[string]InstanceInfo)
 	for _, instance := range instances {
 		key := fmt.Sprintf("%s-%s", instance.InstanceType, instance.Engine)
-		if agg, exists := aggregated[key]; exists {
+		agg, exists := aggregated[key]
+		if exists {
 			agg.NumberOfInstances += instance.NumberOfInstances
-			aggregated[key] = agg
 		} else {
-			aggregated[key] = instance
+			agg = instance
+			agg.Identifiers = nil
+		}
+		if instance.Identifier != "" {
+			agg.Identifiers = append(agg.Identifiers, instance.Identifier)
 		}
+		aggregated[key] = agg
 	}
 
 	var aggregatedList []InstanceInfo
@@ -538,9 +721,122 @@ func InitializeLogger() {
 
 func ParseFlags() {
 	flag.StringVar(&Region, "region", "", "AWS region")
+	serviceFlag := flag.String("service", ServiceRDS, "Service whose running instances are priced: rds (DB instances), ec2, elasticache (cache nodes), opensearch (domain nodes), redshift (cluster nodes) or memorydb (cluster nodes)")
+	flag.StringVar(&TerraformFile, "terraform", "", "Read the inventory from a 'terraform show -json' state or plan file instead of the AWS account")
+	flag.StringVar(&CloudFormationFile, "cloudformation", "", "Read the inventory from a JSON or YAML CloudFormation template instead of the AWS account")
+	cloudFormationParametersFlag := flag.String("cloudformation-parameters", "", "Comma separated Key=Value CloudFormation parameter overrides")
+	filterTagsFlag := flag.String("filter-tag", "", "Comma separated Key=Value tags the instances must all have, e.g. env=prod")
+	flag.StringVar(&GroupByTag, "group-by-tag", "", "Break out the pricing tables and savings totals by the value of this tag")
+	flag.IntVar(&UptimeDays, "uptime-days", 0, "Only recommend reservations for instances running continuously over this many days of CloudWatch history (e.g. 30 to 90, 0 disables the check)")
+	flag.Float64Var(&MinUptimePercent, "min-uptime-percent", 95, "Share of the -uptime-days history an instance must have been running to be considered continuously running")
+	flag.IntVar(&RightsizingDays, "rightsizing-days", 0, "Suggest smaller classes based on this many days of CloudWatch CPU, memory and connections metrics (0 disables rightsizing)")
+	flag.BoolVar(&CompareGravitonFlag, "graviton", false, "Compare the costs of migrating x86 instances to their Graviton equivalent and reserving them")
+	flag.BoolVar(&CompareSavingsPlansFlag, "savings-plans", false, "Compare the EC2 reservations with the Compute and EC2 Instance Savings Plans covering the same instances")
+	commitmentDiscountsFlag := flag.String("commitment-discounts", "", "Comma separated Years=Percent discounts of an hourly spend commitment, such as a Database Savings Plan, to compare with the reservations of the instances of all the database services in the -commitment-regions, e.g. 1=20 or 1=20,3=35")
+	commitmentRegionsFlag := flag.String("commitment-regions", "", "Comma separated regions whose database spend is covered by the -commitment-discounts commitment along with -region, e.g. eu-west-1,eu-central-1")
+	flag.StringVar(&ForecastFile, "forecast", "", "JSON or YAML scenario file of the monthly instance additions and removals, used to recommend reservation quantities over 36 months")
+	flag.IntVar(&CoverageDays, "coverage-days", 0, "Compute the optimal number of reservations from the hourly running counts over this many days of CloudWatch history (0 disables it)")
+	hoursConventionFlag := flag.String("hours-convention", "", "Hours in a month used for the costs: 730 (AWS monthly prices) or calendar (actual hours of the term from -term-start), 8760/12 being an alias of 730. Defaults to calendar with -term-start, 730 otherwise")
+	termStartFlag := flag.String("term-start", "", "Reservation start date (YYYY-MM-DD) used to compute the exact calendar hours of the terms")
+	currencyFlag := flag.String("currency", "", "Currency of the reported costs, e.g. EUR or GBP, converted with the -rates-file exchange rates. Defaults to the pricing currency of the region (CNY in China, USD elsewhere)")
+	ratesFileFlag := flag.String("rates-file", "", "JSON or YAML file of the exchange rates per US dollar, e.g. {\"EUR\": 0.92, \"GBP\": 0.79}")
+	flag.StringVar(&DiscountsFile, "discounts", "", "JSON or YAML file of the discounts (EDP, private pricing) applied to the list prices: a global percent and per service or per region overrides")
+	flag.StringVar(&PlanOutFile, "plan-out", "", "Write the recommended reservation purchases to this JSON plan file")
+	planTermFlag := flag.Int("plan-term", 1, "Term in years of the reservations of the -plan-out plan (1 or 3)")
+	flag.BoolVar(&ValidateOfferings, "validate-offerings", false, "Check the reserved options against the RDS reserved offerings of the region, reporting the ones not offered or priced differently")
+	flag.StringVar(&StatusPolicy, "status-policy", StatusPolicyActive, "Instance statuses to include: available (only available instances), active (also busy ones such as backing-up or modifying) or all (also stopped ones)")
 	logLevelFlag := flag.String("logLevel", "info", "Log level (debug, info, error)")
 	flag.Parse()
 
+	if TerraformFile != "" && CloudFormationFile != "" {
+		fmt.Println("Invalid -cloudformation: only one of -terraform and -cloudformation can be used")
+		os.Exit(1)
+	}
+	// The CloudWatch history is looked up by the identifiers of the running instances
+	if UptimeDays > 0 && (TerraformFile != "" || CloudFormationFile != "") {
+		fmt.Println("Invalid -uptime-days: not supported with the -terraform and -cloudformation inventories, which have no CloudWatch history")
+		os.Exit(1)
+	}
+	if RightsizingDays > 0 && (TerraformFile != "" || CloudFormationFile != "") {
+		fmt.Println("Invalid -rightsizing-days: not supported with the -terraform and -cloudformation inventories, which have no CloudWatch metrics")
+		os.Exit(1)
+	}
+
+	var err error
+	Service, err = ParseService(*serviceFlag)
+	if err != nil {
+		fmt.Printf("Invalid -service: %v\n", err)
+		os.Exit(1)
+	}
+
+	CloudFormationParameters, err = ParseKeyValuePairs(*cloudFormationParametersFlag)
+	if err != nil {
+		fmt.Printf("Invalid -cloudformation-parameters: %v\n", err)
+		os.Exit(1)
+	}
+
+	FilterTags, err = ParseKeyValuePairs(*filterTagsFlag)
+	if err != nil {
+		fmt.Printf("Invalid -filter-tag: %v\n", err)
+		os.Exit(1)
+	}
+
+	CommitmentDiscounts, err = ParseCommitmentDiscounts(*commitmentDiscountsFlag)
+	if err != nil {
+		fmt.Printf("Invalid -commitment-discounts: %v\n", err)
+		os.Exit(1)
+	}
+
+	CommitmentRegions, err = ParseCommitmentRegions(*commitmentRegionsFlag, Region)
+	if err != nil {
+		fmt.Printf("Invalid -commitment-regions: %v\n", err)
+		os.Exit(1)
+	}
+	if *commitmentRegionsFlag != "" && (len(CommitmentDiscounts) == 0 || TerraformFile != "" || CloudFormationFile != "") {
+		fmt.Println("Invalid -commitment-regions: only supported with -commitment-discounts and the AWS account inventory")
+		os.Exit(1)
+	}
+
+	IncludedStatuses, err = ParseStatusPolicy(StatusPolicy, Service)
+	if err != nil {
+		fmt.Printf("Invalid -status-policy: %v\n", err)
+		os.Exit(1)
+	}
+
+	HoursConvention, TermStart, err = ParseHoursConvention(*hoursConventionFlag, *termStartFlag)
+	if err != nil {
+		fmt.Printf("Invalid -hours-convention: %v\n", err)
+		os.Exit(1)
+	}
+
+	var ratesProvider ExchangeRatesProvider
+	if *ratesFileFlag != "" {
+		ratesProvider = FileRatesProvider{Path: *ratesFileFlag}
+	}
+	if err := SetCurrency(pricingCurrency(Region), *currencyFlag, ratesProvider); err != nil {
+		fmt.Printf("Invalid -currency: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *planTermFlag != 1 && *planTermFlag != 3 {
+		fmt.Printf("Invalid -plan-term: %d, expected 1 or 3\n", *planTermFlag)
+		os.Exit(1)
+	}
+	PlanTerm = fmt.Sprintf("%d Year", *planTermFlag)
+
+	if DiscountsFile != "" {
+		Discounts, err = LoadDiscountConfig(DiscountsFile)
+		if err != nil {
+			fmt.Printf("Invalid -discounts: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if err := checkServiceFlags(Service); err != nil {
+		fmt.Printf("Invalid -service: %v\n", err)
+		os.Exit(1)
+	}
+
 	switch strings.ToLower(*logLevelFlag) {
 	case "debug":
 		LogLevel = Debug
@@ -552,71 +848,137 @@ func ParseFlags() {
 
 }
 
-func FetchAndAggregateInstances(region string) ([]InstanceInfo, error) {
-	instanceInfos, err := GetRunningRdsInstances(region)
+// ParseKeyValuePairs parses a comma separated list of Key=Value pairs.
+func ParseKeyValuePairs(value string) (map[string]string, error) {
+	pairs := make(map[string]string)
+	if value == "" {
+		return pairs, nil
+	}
+	for _, pair := range strings.Split(value, ",") {
+		key, val, found := strings.Cut(pair, "=")
+		if !found || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("invalid pair %q, expected Key=Value", pair)
+		}
+		pairs[strings.TrimSpace(key)] = strings.TrimSpace(val)
+	}
+	return pairs, nil
+}
+
+// FetchInstances returns the instances from the selected inventory source that
+// match the tag filters, as well as the instances excluded by the status policy.
+func FetchInstances(region string) ([]InstanceInfo, []ExcludedInstance, error) {
+	var instanceInfos []InstanceInfo
+	var excluded []ExcludedInstance
+	var err error
+
+	switch {
+	case Service == ServiceEC2:
+		instanceInfos, excluded, err = GetRunningEC2Instances(region, IncludedStatuses)
+	case Service == ServiceElastiCache:
+		instanceInfos, excluded, err = GetRunningElastiCacheNodes(region, IncludedStatuses)
+	case Service == ServiceOpenSearch:
+		instanceInfos, excluded, err = GetRunningOpenSearchNodes(region, IncludedStatuses)
+	case Service == ServiceRedshift:
+		instanceInfos, excluded, err = GetRunningRedshiftClusters(region, IncludedStatuses)
+	case Service == ServiceMemoryDB:
+		instanceInfos, excluded, err = GetRunningMemoryDBClusters(region, IncludedStatuses)
+	case TerraformFile != "":
+		instanceInfos, err = GetTerraformInstances(TerraformFile)
+	case CloudFormationFile != "":
+		instanceInfos, err = GetCloudFormationInstances(CloudFormationFile, region, CloudFormationParameters)
+	default:
+		instanceInfos, excluded, err = GetRunningRdsInstances(region, IncludedStatuses)
+		if err == nil && UptimeDays > 0 {
+			var notContinuous []ExcludedInstance
+			instanceInfos, notContinuous, err = FilterInstancesByUptime(region, instanceInfos, UptimeDays, MinUptimePercent)
+			excluded = append(excluded, notContinuous...)
+		}
+	}
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	instanceInfos = FilterInstancesByTags(instanceInfos, FilterTags)
+	if Service == ServiceRDS {
+		var unpriced []ExcludedInstance
+		instanceInfos, unpriced = excludeUnpricedEngines(instanceInfos)
+		excluded = append(excluded, unpriced...)
+	}
+	return instanceInfos, excluded, nil
+}
+
+// excludeUnpricedEngines leaves out the instances whose engine isn't priced by the
+// tool, such as Db2 or the RDS Custom ones, returning them as excluded instances.
+func excludeUnpricedEngines(instances []InstanceInfo) ([]InstanceInfo, []ExcludedInstance) {
+	var priced []InstanceInfo
+	var excluded []ExcludedInstance
+	for _, instance := range instances {
+		if instance.Engine != "Unknown" {
+			priced = append(priced, instance)
+			continue
+		}
+		excluded = append(excluded, ExcludedInstance{
+			Identifier:   instance.Identifier,
+			InstanceType: instance.InstanceType,
+			Engine:       instance.Engine,
+			Status:       instance.Status,
+			Reason:       "Engine not supported, only MySQL, PostgreSQL, MariaDB, Oracle, SQL Server, Aurora, DocumentDB and Neptune are priced",
+		})
 	}
-	return aggregateInstances(instanceInfos), nil
+	return priced, excluded
 }
 
 func ProcessPricingData(region string, runningInstances []InstanceInfo) ([]PricingData, []PricingData) {
-	instanceTypeData, err := FetchAndProcessRDSData(region, runningInstances)
+	switch Service {
+	case ServiceEC2:
+		return ProcessEC2PricingData(region, runningInstances)
+	case ServiceElastiCache:
+		return ProcessElastiCachePricingData(region, runningInstances)
+	case ServiceOpenSearch:
+		return ProcessOpenSearchPricingData(region, runningInstances)
+	case ServiceRedshift:
+		return ProcessRedshiftPricingData(region, runningInstances)
+	case ServiceMemoryDB:
+		return ProcessMemoryDBPricingData(region, runningInstances)
+	}
+
+	// DocumentDB, Neptune, Oracle and SQL Server are priced from the AWS Price List API
+	var rdsInstances, priceListInstances []InstanceInfo
+	for _, instance := range runningInstances {
+		if _, ok := priceListEngines[instance.Engine]; ok {
+			priceListInstances = append(priceListInstances, instance)
+		} else {
+			rdsInstances = append(rdsInstances, instance)
+		}
+	}
+	data1Year, data3Years := ProcessPriceListEnginePricingData(region, priceListInstances)
+
+	instanceTypeData, err := FetchAndProcessRDSData(region, rdsInstances)
 	if err != nil {
 		errorLog.Printf("Failed to fetch RDS data: %v", err)
-		return nil, nil
+		return data1Year, data3Years
 	}
 
-	return ProcessInstanceTypes(instanceTypeData, region, runningInstances)
+	rdsData1Year, rdsData3Years := ProcessInstanceTypes(instanceTypeData, region, rdsInstances)
+	return append(rdsData1Year, data1Year...), append(rdsData3Years, data3Years...)
 }
 
 func ProcessReservedPricing(instance ec2instancesinfo.RDSInstance, region string, engine string, numberOfInstances int) ([]PricingData, []PricingData) {
 	var data1Year, data3Years []PricingData
 	if servicePricing, ok := instance.Pricing[region]; ok {
-		var serviceRDSPricing ec2instancesinfo.RDSPricing
-		switch engine {
-		case "MySQL":
-			serviceRDSPricing = servicePricing.MySQL
-		case "PostgreSQL":
-			serviceRDSPricing = servicePricing.PostgreSQL
-			// Add cases for other database engines as needed
-		}
-
-		reservedOptions := []struct {
-			Term  string
-			Price float64
-		}{
-			{"yrTerm1Standard.noUpfront", serviceRDSPricing.Reserved.StandardNoUpfront1Year},
-			{"yrTerm3Standard.noUpfront", serviceRDSPricing.Reserved.StandardNoUpfront3Years},
-			{"yrTerm1Standard.partialUpfront", serviceRDSPricing.Reserved.StandardPartiallUpfront1Year},
-			{"yrTerm3Standard.partialUpfront", serviceRDSPricing.Reserved.StandardPartialUpfront3Years},
-			{"yrTerm1Standard.allUpfront", serviceRDSPricing.Reserved.StandardAllUpfront1Year},
-			{"yrTerm3Standard.allUpfront", serviceRDSPricing.Reserved.StandardAllUpfront3Years},
-			{"yrTerm1Convertible.noUpfront", serviceRDSPricing.Reserved.ConvertibleNoUpfront1Year},
-			{"yrTerm3Convertible.noUpfront", serviceRDSPricing.Reserved.ConvertibleNoUpfront3Years},
-			{"yrTerm1Convertible.partialUpfront", serviceRDSPricing.Reserved.ConvertiblePartiallUpfront1Year},
-			{"yrTerm3Convertible.partialUpfront", serviceRDSPricing.Reserved.ConvertiblePartialUpfront3Years},
-			{"yrTerm1Convertible.allUpfront", serviceRDSPricing.Reserved.ConvertibleAllUpfront1Year},
-			{"yrTerm3Convertible.allUpfront", serviceRDSPricing.Reserved.ConvertibleAllUpfront3Years},
-		}
-
-		for _, option := range reservedOptions {
-			if option.Price != 0 {
-				reservedRow := ProcessReservedOption(instance.InstanceType, option.Term, option.Price, HoursInMonth, serviceRDSPricing.OnDemand, numberOfInstances)
-				if strings.Contains(option.Term, "yrTerm1") {
-					data1Year = append(data1Year, reservedRow)
-				} else {
-					data3Years = append(data3Years, reservedRow)
-				}
-			}
-		}
+		serviceRDSPricing := rdsEnginePricing(servicePricing, engine)
+		discount := discountFactor("RDS", region)
+
+		data1Year, data3Years = reservedRows(instance.InstanceType, rdsReservedOptions(serviceRDSPricing), serviceRDSPricing.OnDemand, discount, numberOfInstances)
 
 		// Set the engine for the processed data
 		for i := range data1Year {
 			data1Year[i].Engine = engine
+			data1Year[i].DiscountPercent = Discounts.DiscountPercent("RDS", region)
 		}
 		for i := range data3Years {
 			data3Years[i].Engine = engine
+			data3Years[i].DiscountPercent = Discounts.DiscountPercent("RDS", region)
 		}
 	} else {
 		debugLog.Printf("No reserved pricing available for the specified service and region")
@@ -625,7 +987,7 @@ func ProcessReservedPricing(instance ec2instancesinfo.RDSInstance, region string
 	return data1Year, data3Years
 }
 
-func PrintPricingTables(data []PricingData, instances []InstanceInfo, term string) {
+func PrintPricingTables(data []PricingData, instances []InstanceInfo, term string, rightsizing *RightsizingReport) {
 	enginesInUse := make(map[string]bool)
 	for _, instanceInfo := range instances {
 		enginesInUse[instanceInfo.Engine] = true
@@ -646,36 +1008,220 @@ func PrintPricingTables(data []PricingData, instances []InstanceInfo, term strin
 		"Total Monthly Cost ($)",
 		"Total Amortized Monthly Cost ($)",
 		"Total Cost for Term ($)",
+		"Databases",
+	}
+	columns = serviceColumns(Service, columns)
+	if DiscountsFile != "" {
+		columns = append(columns,
+			"Discount (%)",
+			"List Total Cost for Term ($)",
+		)
+	}
+	if ValidateOfferings {
+		columns = append(columns, "AWS Offering")
+	}
+	if rightsizing != nil {
+		columns = append(columns,
+			"Suggested Instance Type",
+			"Suggested Total Cost for Term ($)",
+			"Rightsizing Savings ($)",
+		)
 	}
 
 	for engine := range enginesInUse {
 		aggregatedData := AggregateCostsByTermAndEngine(data, instances, term, engine)
+		if rightsizing != nil {
+			aggregatedData = rightsizing.ApplyToPricingData(aggregatedData, term)
+		}
 		title := fmt.Sprintf("## %s Term Costs for %s", term, engine)
 		PrintMarkdownTable(aggregatedData, columns, title)
 		//PrintMarkdownTable(data, columns, title)
 	}
 }
 
+// validateOfferings marks the reserved options of both terms not offered by AWS,
+// printing the options not offered or priced differently.
+func validateOfferings(region string, data1Year, data3Years []PricingData) {
+	var issues []OfferingIssue
+	for _, data := range [][]PricingData{data1Year, data3Years} {
+		termIssues, err := ValidateReservedOfferings(region, data)
+		if err != nil {
+			errorLog.Printf("Failed to validate the reserved offerings: %v", err)
+			return
+		}
+		issues = append(issues, termIssues...)
+	}
+	PrintOfferingIssues(issues)
+}
+
 func main() {
 	InitializeLogger()
+	if len(os.Args) > 1 && os.Args[1] == "purchase" {
+		RunPurchase(os.Args[2:])
+		return
+	}
 	ParseFlags()
 
 	if Region == "" {
-		fmt.Println("Usage: script -region <region> [-logLevel debug/info/error]")
+		fmt.Println("Usage: script -region <region> [-service rds/ec2/elasticache/opensearch/redshift/memorydb] [-terraform <file> | -cloudformation <file>] [-status-policy available/active/all] [-filter-tag key=value] [-group-by-tag key] [-uptime-days <days>] [-rightsizing-days <days>] [-graviton] [-savings-plans] [-commitment-discounts 1=<percent>,3=<percent> [-commitment-regions <regions>]] [-forecast <file>] [-coverage-days <days>] [-hours-convention 730/calendar] [-term-start YYYY-MM-DD] [-currency <code> -rates-file <file>] [-discounts <file>] [-plan-out <file> -plan-term 1/3] [-validate-offerings] [-logLevel debug/info/error]")
+		os.Exit(1)
+	}
+
+	var scenario *ForecastScenario
+	if ForecastFile != "" {
+		var err error
+		scenario, err = LoadForecastScenario(ForecastFile)
+		if err != nil {
+			fmt.Printf("Invalid -forecast: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if err := CheckPricingAvailable(Region); err != nil {
+		fmt.Println(err)
 		os.Exit(1)
 	}
 
-	aggregatedInstances, err := FetchAndAggregateInstances(Region)
+	if HoursConvention == HoursConventionCalendar {
+		fmt.Printf("Terms starting on %s: 1 Year of %.0f hours, 3 Year of %.0f hours\n", TermStart.Format("2006-01-02"), termHours(1), termHours(3))
+	}
+
+	instances, excludedInstances, err := FetchInstances(Region)
 	if err != nil {
 		errorLog.Printf("Failed to process instances: %v", err)
 		return
 	}
 
-	pricingData1Year, pricingData3Years := ProcessPricingData(Region, aggregatedInstances)
+	var rightsizing *RightsizingReport
+	if RightsizingDays > 0 {
+		rightsizing, err = GetRightsizingReport(Region, instances, RightsizingDays)
+		if err != nil {
+			errorLog.Printf("Failed to compute rightsizing suggestions: %v", err)
+			return
+		}
+	}
+
+	// The commitment, the forecast, the coverage and the purchase plan cover all the instances,
+	// priced once along with the only group when they aren't grouped by tag
+	var allInstances []InstanceInfo
+	var allData1Year, allData3Years []PricingData
+
+	var summaries []SavingsSummary
+	for _, group := range GroupInstancesByTag(instances, GroupByTag) {
+		if GroupByTag != "" {
+			fmt.Printf("\n# %s: %s\n", GroupByTag, group.Name)
+		}
+
+		aggregatedInstances := aggregateInstances(group.Instances)
+		pricingData1Year, pricingData3Years := ProcessPricingData(Region, aggregatedInstances)
+		if ValidateOfferings {
+			validateOfferings(Region, pricingData1Year, pricingData3Years)
+		}
+		if GroupByTag == "" {
+			allInstances, allData1Year, allData3Years = aggregatedInstances, pricingData1Year, pricingData3Years
+		}
+
+		debugLog.Printf("Main Data 1 year: %v", pricingData1Year)
+		debugLog.Printf("Main Data 3 years: %v", pricingData3Years)
+
+		PrintPricingTables(pricingData1Year, aggregatedInstances, "1 Year", rightsizing)
+		PrintPricingTables(pricingData3Years, aggregatedInstances, "3 Year", rightsizing)
+
+		if Service == ServiceRDS {
+			previous, err := PreviousGenerationInstances(aggregatedInstances)
+			if err != nil {
+				errorLog.Printf("Failed to detect previous generation instance types: %v", err)
+			}
+			upgrades, err := ComparePreviousGeneration(Region, aggregatedInstances, pricingData1Year, pricingData3Years)
+			if err != nil {
+				errorLog.Printf("Failed to compare previous generation pricing: %v", err)
+			}
+			PrintPreviousGenerationWarnings(previous, upgrades)
+		}
+
+		if Service == ServiceRedshift {
+			comparisons, err := CompareRedshiftServerless(Region, group.Instances)
+			if err != nil {
+				errorLog.Printf("Failed to compare Redshift Serverless pricing: %v", err)
+			}
+			PrintRedshiftServerless(comparisons)
+		}
 
-	debugLog.Printf("Main Data 1 year: %v", pricingData1Year)
-	debugLog.Printf("Main Data 3 years: %v", pricingData3Years)
+		if CompareGravitonFlag {
+			comparisons, err := CompareGraviton(Region, aggregatedInstances, pricingData1Year, pricingData3Years)
+			if err != nil {
+				errorLog.Printf("Failed to compare Graviton pricing: %v", err)
+			}
+			PrintMigrationComparison(comparisons, "Graviton Migration Savings", "Graviton", "Migration + Reservation")
+		}
 
-	PrintPricingTables(pricingData1Year, aggregatedInstances, "1 Year")
-	PrintPricingTables(pricingData3Years, aggregatedInstances, "3 Year")
+		if CompareSavingsPlansFlag {
+			comparisons, err := CompareSavingsPlans(Region, aggregatedInstances, pricingData1Year, pricingData3Years)
+			if err != nil {
+				errorLog.Printf("Failed to compare Savings Plans pricing: %v", err)
+			}
+			PrintSavingsPlansComparison(comparisons)
+		}
+
+		summaries = append(summaries,
+			SummarizeSavings(group.Name, pricingData1Year, aggregatedInstances, "1 Year"),
+			SummarizeSavings(group.Name, pricingData3Years, aggregatedInstances, "3 Year"))
+	}
+
+	repriced := false
+	if GroupByTag != "" {
+		PrintSavingsSummary(summaries, GroupByTag)
+		allInstances = aggregateInstances(instances)
+		if len(CommitmentDiscounts) > 0 || PlanOutFile != "" || CoverageDays > 0 {
+			allData1Year, allData3Years = ProcessPricingData(Region, allInstances)
+			repriced = true
+		}
+	}
+	if ValidateOfferings && repriced {
+		// Already reported with the pricing tables, only mark the options not offered
+		for _, data := range [][]PricingData{allData1Year, allData3Years} {
+			if _, err := ValidateReservedOfferings(Region, data); err != nil {
+				errorLog.Printf("Failed to validate the reserved offerings: %v", err)
+			}
+		}
+	}
+
+	if len(CommitmentDiscounts) > 0 {
+		current := commitmentSpend(Region, Service, allInstances, allData1Year, allData3Years)
+		spends := CollectCommitmentSpend(CommitmentRegions, StatusPolicy, current)
+		PrintCommitmentComparison(CompareCommitment(spends, CommitmentDiscounts), spends)
+	}
+	if scenario != nil {
+		plans, err := ForecastReservations(Region, allInstances, scenario)
+		if err != nil {
+			errorLog.Printf("Failed to forecast the reservations: %v", err)
+		} else {
+			PrintForecast(plans)
+		}
+	}
+	if CoverageDays > 0 {
+		curves, err := OptimizeCoverage(Region, instances, allData1Year, allData3Years, CoverageDays)
+		if err != nil {
+			errorLog.Printf("Failed to optimize the reservation coverage: %v", err)
+		} else {
+			PrintCoverageCurves(curves, CoverageDays)
+		}
+	}
+	if PlanOutFile != "" {
+		data := allData1Year
+		if PlanTerm == "3 Year" {
+			data = allData3Years
+		}
+		plan := BuildPurchasePlan(Region, data, allInstances, PlanTerm)
+		if err := WritePurchasePlan(plan, PlanOutFile); err != nil {
+			errorLog.Printf("Failed to write the purchase plan: %v", err)
+		} else {
+			fmt.Printf("\nWrote %d recommended purchases to %s\n", len(plan.Purchases), PlanOutFile)
+		}
+	}
+	PrintInventory(instances)
+	if rightsizing != nil {
+		PrintRightsizingSuggestions(rightsizing)
+	}
+	PrintExcludedInstances(excludedInstances)
 }
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/memorydb"
+	memorydbtypes "github.com/aws/aws-sdk-go-v2/service/memorydb/types"
+)
+
+// memoryDBPricing holds the MemoryDB node prices of a region by engine, which
+// aren't part of the ec2-instances-info pricing data and are fetched from the AWS
+// Price List API.
+type memoryDBPricing struct {
+	Redis  map[string]priceListInstancePricing
+	Valkey map[string]priceListInstancePricing
+}
+
+// The MemoryDB prices are only fetched once per region.
+var memoryDBData = make(map[string]*memoryDBPricing)
+
+// memoryDBPricingData returns the MemoryDB node type prices of the region.
+func memoryDBPricingData(region string) (*memoryDBPricing, error) {
+	if data, ok := memoryDBData[region]; ok {
+		return data, nil
+	}
+	products, err := getPriceListProducts(region, "AmazonMemoryDB", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	currency := pricingCurrency(region)
+	data := &memoryDBPricing{
+		Redis: priceListInstanceTypes(products, currency, func(product priceListProduct) bool {
+			return !product.mentions(EngineValkey)
+		}),
+		Valkey: priceListInstanceTypes(products, currency, func(product priceListProduct) bool {
+			return product.mentions(EngineValkey)
+		}),
+	}
+	memoryDBData[region] = data
+	return data, nil
+}
+
+// nodeTypePricing returns the prices of a node type for the engine, the Redis OSS
+// ones applying when the Valkey ones aren't listed.
+func (p *memoryDBPricing) nodeTypePricing(nodeType, engine string) priceListInstancePricing {
+	if pricing, ok := p.Valkey[nodeType]; ok && engine == EngineValkey {
+		return pricing
+	}
+	return p.Redis[nodeType]
+}
+
+// GetRunningMemoryDBClusters fetches the MemoryDB clusters whose status is one of
+// the given statuses, each entry counting the nodes of all its shards. The others
+// are returned as excluded instances.
+func GetRunningMemoryDBClusters(region string, statuses []string) ([]InstanceInfo, []ExcludedInstance, error) {
+	cfg, err := config.LoadDefaultConfig(context.TODO(), config.WithRegion(region))
+	if err != nil {
+		errorLog.Printf("Error loading AWS config: %v", err)
+		return nil, nil, err
+	}
+
+	svc := memorydb.NewFromConfig(cfg)
+	paginator := memorydb.NewDescribeClustersPaginator(svc, &memorydb.DescribeClustersInput{
+		ShowShardDetails: aws.Bool(true),
+	})
+
+	var instances []InstanceInfo
+	var excluded []ExcludedInstance
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			errorLog.Printf("Error describing MemoryDB clusters: %v", err)
+			return nil, nil, err
+		}
+
+		for _, cluster := range page.Clusters {
+			info := memoryDBClusterInfo(cluster)
+			if !isStatusIncluded(info.Status, statuses) {
+				excluded = append(excluded, ExcludedInstance{
+					Identifier:   info.Identifier,
+					InstanceType: info.InstanceType,
+					Engine:       info.Engine,
+					Status:       info.Status,
+					Reason:       "Status not included by the status policy",
+				})
+				continue
+			}
+
+			// Listing the tags takes a call per cluster, only do it when they're used
+			if len(FilterTags) > 0 || GroupByTag != "" {
+				tags, err := svc.ListTags(context.TODO(), &memorydb.ListTagsInput{ResourceArn: cluster.ARN})
+				if err != nil {
+					errorLog.Printf("Error listing the tags of %s: %v", info.Identifier, err)
+					return nil, nil, err
+				}
+				info.Tags = tagsFromMemoryDBTags(tags.TagList)
+			}
+			instances = append(instances, info)
+		}
+	}
+
+	debugLog.Printf("Found running MemoryDB clusters: %v", instances)
+	debugLog.Printf("Excluded MemoryDB clusters: %v", excluded)
+	return instances, excluded, nil
+}
+
+// memoryDBClusterInfo describes a cluster, counting the nodes of all its shards.
+func memoryDBClusterInfo(cluster memorydbtypes.Cluster) InstanceInfo {
+	info := InstanceInfo{
+		InstanceType:  aws.ToString(cluster.NodeType),
+		Engine:        cacheEngine(aws.ToString(cluster.Engine)),
+		MultiAZ:       strings.EqualFold(string(cluster.AvailabilityMode), string(memorydbtypes.AZStatusMultiAZ)),
+		Status:        aws.ToString(cluster.Status),
+		Identifier:    aws.ToString(cluster.Name),
+		ARN:           aws.ToString(cluster.ARN),
+		EngineVersion: aws.ToString(cluster.EngineVersion),
+	}
+
+	zones := make(map[string]bool)
+	for _, shard := range cluster.Shards {
+		info.NumberOfInstances += int(aws.ToInt32(shard.NumberOfNodes))
+		for _, node := range shard.Nodes {
+			zones[aws.ToString(node.AvailabilityZone)] = true
+			if created := aws.ToTime(node.CreateTime); !created.IsZero() && (info.CreationTime.IsZero() || created.Before(info.CreationTime)) {
+				info.CreationTime = created
+			}
+		}
+	}
+	if info.NumberOfInstances == 0 {
+		info.NumberOfInstances = int(aws.ToInt32(cluster.NumberOfShards)) // At least a primary node per shard
+	}
+
+	var sortedZones []string
+	for zone := range zones {
+		sortedZones = append(sortedZones, zone)
+	}
+	sort.Strings(sortedZones)
+	info.AvailabilityZone = strings.Join(sortedZones, ", ")
+	return info
+}
+
+// ProcessMemoryDBPricingData returns the on-demand and reserved pricing rows of each
+// node type and engine of the aggregated MemoryDB clusters, for both terms.
+func ProcessMemoryDBPricingData(region string, runningInstances []InstanceInfo) ([]PricingData, []PricingData) {
+	prices, err := memoryDBPricingData(region)
+	if err != nil {
+		errorLog.Printf("Failed to fetch MemoryDB data: %v", err)
+		return nil, nil
+	}
+
+	discount := discountFactor(serviceName(ServiceMemoryDB), region)
+	discountPercent := Discounts.DiscountPercent(serviceName(ServiceMemoryDB), region)
+
+	processed := make(map[string]bool)
+	var finalData1Year, finalData3Years []PricingData
+	for _, instance := range runningInstances {
+		key := fmt.Sprintf("%s-%s", instance.InstanceType, instance.Engine)
+		if processed[key] {
+			continue
+		}
+		processed[key] = true
+
+		pricing := prices.nodeTypePricing(instance.InstanceType, instance.Engine)
+		if pricing.OnDemand == 0 {
+			debugLog.Printf("No MemoryDB pricing for %s in %s", instance.InstanceType, region)
+			continue
+		}
+
+		onDemand1Year, onDemand3Years := onDemandRows(instance.InstanceType, region, pricing.OnDemand*discount, instance.NumberOfInstances)
+		reserved1Year, reserved3Years := reservedRows(instance.InstanceType, priceListReservedOptions(pricing), pricing.OnDemand, discount, instance.NumberOfInstances)
+		data1Year := append([]PricingData{onDemand1Year}, reserved1Year...)
+		data3Years := append([]PricingData{onDemand3Years}, reserved3Years...)
+
+		for _, data := range [][]PricingData{data1Year, data3Years} {
+			for i := range data {
+				data[i].Engine = instance.Engine
+				data[i].DiscountPercent = discountPercent
+			}
+		}
+		finalData1Year = append(finalData1Year, data1Year...)
+		finalData3Years = append(finalData3Years, data3Years...)
+	}
+
+	debugLog.Printf("MemoryDB Data 1 year: %v", finalData1Year)
+	debugLog.Printf("MemoryDB Data 3 years: %v", finalData3Years)
+	return finalData1Year, finalData3Years
+}
+
+func tagsFromMemoryDBTags(tagList []memorydbtypes.Tag) map[string]string {
+	tags := make(map[string]string, len(tagList))
+	for _, tag := range tagList {
+		tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	return tags
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	ec2instancesinfo "github.com/LeanerCloud/ec2-instances-info"
+	"github.com/olekukonko/tablewriter"
+)
+
+// MigrationComparison compares the term costs of an instance type with the costs
+// of the instance type it could be migrated to.
+type MigrationComparison struct {
+	InstanceType           string
+	TargetInstanceType     string
+	Engine                 string
+	Term                   string
+	NumberOfInstances      int
+	OnDemandCost           float64
+	BestReservedCost       float64
+	TargetOnDemandCost     float64
+	TargetBestReservedCost float64
+	Savings                float64 // Savings of migrating and reserving over staying on-demand
+	SavingsPercent         float64
+}
+
+// migrationTarget returns the instance type to migrate to, or an empty string when
+// the instance type should be left alone.
+type migrationTarget func(rdsData ec2instancesinfo.RDSInstanceData, current ec2instancesinfo.RDSInstance, engine string) string
+
+// CompareMigration prices the migration target of each instance type of the aggregated
+// instances and compares it with the current costs for both terms.
+func CompareMigration(region string, instances []InstanceInfo, data1Year, data3Years []PricingData, target migrationTarget) ([]MigrationComparison, error) {
+	rdsData, err := ec2instancesinfo.RDSData()
+	if err != nil {
+		errorLog.Printf("Error fetching RDS data: %v", err)
+		return nil, err
+	}
+
+	targetTypes := make(map[string]string)
+	var targetInstances []InstanceInfo
+	for _, instance := range instances {
+		current := findRDSInstanceType(*rdsData, instance.InstanceType)
+		if current == nil {
+			continue
+		}
+
+		targetType := target(*rdsData, *current, instance.Engine)
+		if targetType == "" {
+			debugLog.Printf("No migration target for %s", instance.InstanceType)
+			continue
+		}
+
+		targetTypes[fmt.Sprintf("%s-%s", instance.InstanceType, instance.Engine)] = targetType
+		migrated := instance
+		migrated.InstanceType = targetType
+		targetInstances = append(targetInstances, migrated)
+	}
+	if len(targetInstances) == 0 {
+		return nil, nil
+	}
+
+	targetInstances = aggregateInstances(targetInstances)
+	target1Year, target3Years := ProcessPricingData(region, targetInstances)
+
+	var comparisons []MigrationComparison
+	for _, term := range []struct {
+		Name     string
+		Current  []PricingData
+		Migrated []PricingData
+	}{
+		{"1 Year", data1Year, target1Year},
+		{"3 Year", data3Years, target3Years},
+	} {
+		currentCosts := CostsByInstanceType(term.Current, instances, term.Name)
+		targetCosts := CostsByInstanceType(term.Migrated, targetInstances, term.Name)
+
+		for key, cost := range currentCosts {
+			targetType, ok := targetTypes[key]
+			if !ok {
+				continue
+			}
+			targetCost, ok := targetCosts[fmt.Sprintf("%s-%s", targetType, cost.Engine)]
+			if !ok {
+				continue
+			}
+
+			// Several types may migrate to the same target type, only count this type's share.
+			share := float64(cost.NumberOfInstances) / float64(targetCost.NumberOfInstances)
+			comparison := MigrationComparison{
+				InstanceType:           cost.InstanceType,
+				TargetInstanceType:     targetType,
+				Engine:                 cost.Engine,
+				Term:                   term.Name,
+				NumberOfInstances:      cost.NumberOfInstances,
+				OnDemandCost:           cost.OnDemandCost,
+				BestReservedCost:       cost.BestReservedCost,
+				TargetOnDemandCost:     targetCost.OnDemandCost * share,
+				TargetBestReservedCost: targetCost.BestReservedCost * share,
+			}
+			comparison.Savings = comparison.OnDemandCost - comparison.TargetBestReservedCost
+			if comparison.OnDemandCost != 0 {
+				comparison.SavingsPercent = comparison.Savings / comparison.OnDemandCost * 100
+			}
+			comparisons = append(comparisons, comparison)
+		}
+	}
+
+	sort.Slice(comparisons, func(i, j int) bool {
+		if comparisons[i].Term != comparisons[j].Term {
+			return comparisons[i].Term < comparisons[j].Term
+		}
+		return comparisons[i].InstanceType+comparisons[i].Engine < comparisons[j].InstanceType+comparisons[j].Engine
+	})
+	return comparisons, nil
+}
+
+// PrintMigrationComparison prints the costs of the current instance types next to
+// the costs of migrating them, the target being described by the given label.
+func PrintMigrationComparison(comparisons []MigrationComparison, title, target, savings string) {
+	if len(comparisons) == 0 {
+		return
+	}
+
+	fmt.Println("\n## " + title)
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader(currencyHeaders([]string{
+		"Instance Type",
+		"Engine",
+		"Number of Instances",
+		"Term",
+		"On-Demand Cost for Term ($)",
+		"Best Reserved Cost for Term ($)",
+		target + " Instance Type",
+		target + " On-Demand Cost for Term ($)",
+		target + " Best Reserved Cost for Term ($)",
+		savings + " Savings ($)",
+		savings + " Savings (%)",
+	}))
+	table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	table.SetCenterSeparator("|")
+
+	for _, comparison := range comparisons {
+		table.Append([]string{
+			comparison.InstanceType,
+			comparison.Engine,
+			fmt.Sprintf("%d", comparison.NumberOfInstances),
+			comparison.Term,
+			formatCost(comparison.OnDemandCost),
+			formatCost(comparison.BestReservedCost),
+			comparison.TargetInstanceType,
+			formatCost(comparison.TargetOnDemandCost),
+			formatCost(comparison.TargetBestReservedCost),
+			formatCost(comparison.Savings),
+			fmt.Sprintf("%.2f", comparison.SavingsPercent),
+		})
+	}
+
+	table.Render()
+}
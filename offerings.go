@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+	"github.com/olekukonko/tablewriter"
+)
+
+// Statuses of the reserved options once checked against the AWS offerings.
+const (
+	OfferingStatusOffered       = "offered"
+	OfferingStatusNotOffered    = "not offered"
+	OfferingStatusPriceMismatch = "price mismatch"
+)
+
+// Relative difference between the pricing data and the AWS offering tolerated
+// before reporting a price mismatch.
+const offeringPriceTolerance = 0.01
+
+// rdsOfferingKey identifies the Single-AZ reserved offering of a class, product,
+// duration and payment option.
+type rdsOfferingKey struct {
+	InstanceType       string
+	ProductDescription string
+	DurationYears      int
+	OfferingType       string
+}
+
+// OfferingIssue is a reserved option of the pricing data that isn't offered by AWS,
+// or whose price differs from the AWS offering.
+type OfferingIssue struct {
+	InstanceType  string
+	Engine        string
+	Term          string
+	PaymentOption string
+	Status        string
+	DataHourly    float64 // Amortized hourly list price of the pricing data
+	AWSHourly     float64 // Amortized hourly price of the AWS offering
+}
+
+// Effective amortized hourly prices of the offerings, cached by class and product
+// as they don't change between the groups of instances.
+var rdsOfferingPrices = make(map[rdsOfferingKey]float64)
+var rdsOfferingsFetched = make(map[string]bool)
+
+// fetchRDSOfferings caches the Single-AZ reserved offerings of a class and product.
+func fetchRDSOfferings(svc *rds.Client, instanceType, productDescription string) error {
+	fetchedKey := instanceType + "-" + productDescription
+	if rdsOfferingsFetched[fetchedKey] {
+		return nil
+	}
+
+	paginator := rds.NewDescribeReservedDBInstancesOfferingsPaginator(svc, &rds.DescribeReservedDBInstancesOfferingsInput{
+		DBInstanceClass:    aws.String(instanceType),
+		MultiAZ:            aws.Bool(false),
+		ProductDescription: aws.String(productDescription),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			errorLog.Printf("Error describing the reserved offerings: %v", err)
+			return err
+		}
+		for _, offering := range page.ReservedDBInstancesOfferings {
+			durationYears := int(math.Round(float64(aws.ToInt32(offering.Duration)) / (365 * 24 * 3600)))
+			hourly := aws.ToFloat64(offering.FixedPrice)/float64(durationYears*365*24) + aws.ToFloat64(offering.UsagePrice)
+			for _, charge := range offering.RecurringCharges {
+				hourly += aws.ToFloat64(charge.RecurringChargeAmount)
+			}
+			rdsOfferingPrices[rdsOfferingKey{instanceType, productDescription, durationYears, aws.ToString(offering.OfferingType)}] = hourly
+		}
+	}
+
+	rdsOfferingsFetched[fetchedKey] = true
+	return nil
+}
+
+// ValidateReservedOfferings checks the reserved options of the pricing data against
+// the reserved offerings of the region, setting the offering status of each row,
+// and returns the options not offered or priced differently.
+func ValidateReservedOfferings(region string, data []PricingData) ([]OfferingIssue, error) {
+	svc, err := newRDSClient(region, "")
+	if err != nil {
+		return nil, err
+	}
+
+	var issues []OfferingIssue
+	reported := make(map[string]bool)
+	for i, row := range data {
+		if row.Term == "On-Demand" {
+			continue
+		}
+
+		productDescription := rdsProductDescription(row.Engine)
+		if productDescription == "" {
+			continue // Priced from the AWS Price List API, not an RDS offering
+		}
+		if err := fetchRDSOfferings(svc, row.InstanceType, productDescription); err != nil {
+			return nil, err
+		}
+
+		durationYears := 1
+		if row.Term == "3 Year" {
+			durationYears = 3
+		}
+		issue := OfferingIssue{
+			InstanceType:  row.InstanceType,
+			Engine:        row.Engine,
+			Term:          row.Term,
+			PaymentOption: row.PaymentOption,
+			// Compare list prices, the discounts aren't part of the offerings, amortizing
+			// the upfront fee over the 8760 hours a year AWS uses
+			DataHourly: listPrice(row.UpfrontCost/float64(hoursPerYear*durationYears)+row.MonthlyCostPerInstance/monthlyHours(durationYears), row.DiscountPercent),
+		}
+
+		awsHourly, offered := rdsOfferingPrices[rdsOfferingKey{row.InstanceType, productDescription, durationYears, offeringTypes[row.PaymentOption]}]
+		issue.AWSHourly = awsHourly
+		switch {
+		case !offered:
+			issue.Status = OfferingStatusNotOffered
+		case math.Abs(issue.DataHourly-awsHourly) > awsHourly*offeringPriceTolerance:
+			issue.Status = OfferingStatusPriceMismatch
+		default:
+			issue.Status = OfferingStatusOffered
+		}
+		data[i].OfferingStatus = issue.Status
+
+		// The pricing rows may be duplicated, only report each option once
+		key := fmt.Sprintf("%s-%s-%s-%s", row.InstanceType, row.Engine, row.Term, row.PaymentOption)
+		if issue.Status != OfferingStatusOffered && !reported[key] {
+			reported[key] = true
+			issues = append(issues, issue)
+		}
+	}
+
+	sort.Slice(issues, func(i, j int) bool {
+		a, b := issues[i], issues[j]
+		return a.InstanceType+a.Engine+a.Term+a.PaymentOption < b.InstanceType+b.Engine+b.Term+b.PaymentOption
+	})
+	return issues, nil
+}
+
+// PrintOfferingIssues lists the reserved options not offered by AWS or whose price
+// differs from the AWS offering.
+func PrintOfferingIssues(issues []OfferingIssue) {
+	fmt.Println("\n## Reserved Offerings Validation")
+	if len(issues) == 0 {
+		fmt.Println("All the reserved options match the AWS offerings.")
+		return
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader(currencyHeaders([]string{
+		"Instance Type",
+		"Engine",
+		"Term",
+		"Payment Option",
+		"Status",
+		"Pricing Data Hourly ($)",
+		"AWS Offering Hourly ($)",
+		"Difference (%)",
+	}))
+	table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	table.SetCenterSeparator("|")
+
+	for _, issue := range issues {
+		awsHourly, difference := "N/A", "N/A"
+		if issue.Status == OfferingStatusPriceMismatch {
+			awsHourly = fmt.Sprintf("%.4f", issue.AWSHourly*ExchangeRate)
+			difference = fmt.Sprintf("%.2f", (issue.DataHourly-issue.AWSHourly)/issue.AWSHourly*100)
+		}
+		table.Append([]string{
+			issue.InstanceType,
+			issue.Engine,
+			issue.Term,
+			issue.PaymentOption,
+			issue.Status,
+			fmt.Sprintf("%.4f", issue.DataHourly*ExchangeRate),
+			awsHourly,
+			difference,
+		})
+	}
+
+	table.Render()
+}
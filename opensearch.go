@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	ec2instancesinfo "github.com/LeanerCloud/ec2-instances-info"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/opensearch"
+	opensearchtypes "github.com/aws/aws-sdk-go-v2/service/opensearch/types"
+)
+
+// All the domains are priced the same, whether they run OpenSearch or Elasticsearch.
+const EngineOpenSearch = "OpenSearch"
+
+// DescribeDomains accepts at most 5 domain names per call.
+const describeDomainsBatchSize = 5
+
+// The OpenSearch pricing data is only loaded once.
+var openSearchData *ec2instancesinfo.OpenSearchInstanceData
+
+// openSearchPricingData returns the OpenSearch instance types and their prices.
+func openSearchPricingData() (*ec2instancesinfo.OpenSearchInstanceData, error) {
+	if openSearchData != nil {
+		return openSearchData, nil
+	}
+	data, err := ec2instancesinfo.OpenSearchData()
+	if err != nil {
+		errorLog.Printf("Error fetching OpenSearch data: %v", err)
+		return nil, err
+	}
+	openSearchData = data
+	return openSearchData, nil
+}
+
+// GetRunningOpenSearchNodes fetches the OpenSearch domains whose status is one of
+// the given statuses, returning an entry for each of their data, dedicated master
+// and UltraWarm node groups. The others are returned as excluded instances.
+func GetRunningOpenSearchNodes(region string, statuses []string) ([]InstanceInfo, []ExcludedInstance, error) {
+	cfg, err := config.LoadDefaultConfig(context.TODO(), config.WithRegion(region))
+	if err != nil {
+		errorLog.Printf("Error loading AWS config: %v", err)
+		return nil, nil, err
+	}
+	svc := opensearch.NewFromConfig(cfg)
+
+	names, err := svc.ListDomainNames(context.TODO(), &opensearch.ListDomainNamesInput{})
+	if err != nil {
+		errorLog.Printf("Error listing OpenSearch domains: %v", err)
+		return nil, nil, err
+	}
+
+	var domainNames []string
+	for _, domain := range names.DomainNames {
+		domainNames = append(domainNames, aws.ToString(domain.DomainName))
+	}
+
+	var instances []InstanceInfo
+	var excluded []ExcludedInstance
+	for start := 0; start < len(domainNames); start += describeDomainsBatchSize {
+		end := min(start+describeDomainsBatchSize, len(domainNames))
+		result, err := svc.DescribeDomains(context.TODO(), &opensearch.DescribeDomainsInput{
+			DomainNames: domainNames[start:end],
+		})
+		if err != nil {
+			errorLog.Printf("Error describing OpenSearch domains: %v", err)
+			return nil, nil, err
+		}
+
+		for _, domain := range result.DomainStatusList {
+			nodes := openSearchNodeGroups(domain)
+			status := openSearchDomainStatus(domain)
+			if !isStatusIncluded(status, statuses) {
+				for _, node := range nodes {
+					excluded = append(excluded, ExcludedInstance{
+						Identifier:   node.Identifier,
+						InstanceType: node.InstanceType,
+						Engine:       node.Engine,
+						Status:       status,
+						Reason:       "Status not included by the status policy",
+					})
+				}
+				continue
+			}
+
+			// Listing the tags takes a call per domain, only do it when they're used
+			var tags map[string]string
+			if len(FilterTags) > 0 || GroupByTag != "" {
+				tagList, err := svc.ListTags(context.TODO(), &opensearch.ListTagsInput{ARN: domain.ARN})
+				if err != nil {
+					errorLog.Printf("Error listing the tags of %s: %v", aws.ToString(domain.DomainName), err)
+					return nil, nil, err
+				}
+				tags = tagsFromOpenSearchTags(tagList.TagList)
+			}
+
+			for _, node := range nodes {
+				node.Status = status
+				node.Tags = tags
+				instances = append(instances, node)
+			}
+		}
+	}
+
+	debugLog.Printf("Found running OpenSearch nodes: %v", instances)
+	debugLog.Printf("Excluded OpenSearch nodes: %v", excluded)
+	return instances, excluded, nil
+}
+
+// openSearchNodeGroups returns the data, dedicated master and UltraWarm nodes of a
+// domain, with their counts from the domain cluster configuration.
+func openSearchNodeGroups(domain opensearchtypes.DomainStatus) []InstanceInfo {
+	clusterConfig := domain.ClusterConfig
+	if clusterConfig == nil {
+		return nil
+	}
+
+	node := InstanceInfo{
+		Engine:        EngineOpenSearch,
+		MultiAZ:       aws.ToBool(clusterConfig.ZoneAwarenessEnabled),
+		ARN:           aws.ToString(domain.ARN),
+		EngineVersion: aws.ToString(domain.EngineVersion),
+	}
+	groups := []struct {
+		Role         string
+		Enabled      bool
+		InstanceType string
+		Count        int32
+	}{
+		{"data", true, string(clusterConfig.InstanceType), aws.ToInt32(clusterConfig.InstanceCount)},
+		{"dedicated master", aws.ToBool(clusterConfig.DedicatedMasterEnabled), string(clusterConfig.DedicatedMasterType), aws.ToInt32(clusterConfig.DedicatedMasterCount)},
+		{"UltraWarm", aws.ToBool(clusterConfig.WarmEnabled), string(clusterConfig.WarmType), aws.ToInt32(clusterConfig.WarmCount)},
+	}
+
+	var nodes []InstanceInfo
+	for _, group := range groups {
+		if !group.Enabled || group.Count == 0 {
+			continue
+		}
+		groupNode := node
+		groupNode.InstanceType = group.InstanceType
+		groupNode.NumberOfInstances = int(group.Count)
+		groupNode.Identifier = fmt.Sprintf("%s (%s)", aws.ToString(domain.DomainName), group.Role)
+		nodes = append(nodes, groupNode)
+	}
+	return nodes
+}
+
+// openSearchDomainStatus returns the processing status of a domain, such as Active
+// or Modifying, derived from its flags when the status isn't returned.
+func openSearchDomainStatus(domain opensearchtypes.DomainStatus) string {
+	switch {
+	case domain.DomainProcessingStatus != "":
+		return string(domain.DomainProcessingStatus)
+	case aws.ToBool(domain.Deleted):
+		return string(opensearchtypes.DomainProcessingStatusTypeDeleting)
+	case !aws.ToBool(domain.Created):
+		return string(opensearchtypes.DomainProcessingStatusTypeCreating)
+	case aws.ToBool(domain.Processing):
+		return string(opensearchtypes.DomainProcessingStatusTypeModifying)
+	}
+	return string(opensearchtypes.DomainProcessingStatusTypeActive)
+}
+
+// openSearchReservedOptions lists the reserved options of an OpenSearch pricing.
+func openSearchReservedOptions(pricing ec2instancesinfo.OpenSearchRegionPricing) []reservedOption {
+	return []reservedOption{
+		{"yrTerm1Standard.noUpfront", pricing.Reserved.YrTerm1StandardNoUpfront},
+		{"yrTerm3Standard.noUpfront", pricing.Reserved.YrTerm3StandardNoUpfront},
+		{"yrTerm1Standard.partialUpfront", pricing.Reserved.YrTerm1StandardPartialUpfront},
+		{"yrTerm3Standard.partialUpfront", pricing.Reserved.YrTerm3StandardPartialUpfront},
+		{"yrTerm3Standard.allUpfront", pricing.Reserved.YrTerm3StandardAllUpfront},
+	}
+}
+
+// ProcessOpenSearchPricingData returns the on-demand and reserved pricing rows of
+// each instance type of the aggregated OpenSearch nodes, for both terms.
+func ProcessOpenSearchPricingData(region string, runningInstances []InstanceInfo) ([]PricingData, []PricingData) {
+	instanceTypes, err := openSearchPricingData()
+	if err != nil {
+		errorLog.Printf("Failed to fetch OpenSearch data: %v", err)
+		return nil, nil
+	}
+
+	prices := make(map[string]ec2instancesinfo.OpenSearchRegionPricing)
+	for _, instanceType := range *instanceTypes {
+		if regionPrices, ok := instanceType.Pricing[region]; ok {
+			prices[instanceType.InstanceType] = regionPrices
+		}
+	}
+
+	discount := discountFactor(serviceName(ServiceOpenSearch), region)
+	discountPercent := Discounts.DiscountPercent(serviceName(ServiceOpenSearch), region)
+
+	processed := make(map[string]bool)
+	var finalData1Year, finalData3Years []PricingData
+	for _, instance := range runningInstances {
+		key := fmt.Sprintf("%s-%s", instance.InstanceType, instance.Engine)
+		if processed[key] {
+			continue
+		}
+		processed[key] = true
+
+		pricing := prices[instance.InstanceType]
+		if pricing.OnDemand == 0 {
+			debugLog.Printf("No OpenSearch pricing for %s in %s", instance.InstanceType, region)
+			continue
+		}
+
+		onDemand1Year, onDemand3Years := onDemandRows(instance.InstanceType, region, pricing.OnDemand*discount, instance.NumberOfInstances)
+		reserved1Year, reserved3Years := reservedRows(instance.InstanceType, openSearchReservedOptions(pricing), pricing.OnDemand, discount, instance.NumberOfInstances)
+		data1Year := append([]PricingData{onDemand1Year}, reserved1Year...)
+		data3Years := append([]PricingData{onDemand3Years}, reserved3Years...)
+
+		for _, data := range [][]PricingData{data1Year, data3Years} {
+			for i := range data {
+				data[i].Engine = instance.Engine
+				data[i].DiscountPercent = discountPercent
+			}
+		}
+		finalData1Year = append(finalData1Year, data1Year...)
+		finalData3Years = append(finalData3Years, data3Years...)
+	}
+
+	debugLog.Printf("OpenSearch Data 1 year: %v", finalData1Year)
+	debugLog.Printf("OpenSearch Data 3 years: %v", finalData3Years)
+	return finalData1Year, finalData3Years
+}
+
+func tagsFromOpenSearchTags(tagList []opensearchtypes.Tag) map[string]string {
+	tags := make(map[string]string, len(tagList))
+	for _, tag := range tagList {
+		tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	return tags
+}
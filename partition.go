@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	ec2instancesinfo "github.com/LeanerCloud/ec2-instances-info"
+)
+
+// AWS partitions, each having its own endpoints, accounts and pricing.
+const (
+	PartitionAWS      = "aws"
+	PartitionChina    = "aws-cn"
+	PartitionGovCloud = "aws-us-gov"
+)
+
+// regionPartition returns the partition of a region.
+func regionPartition(region string) string {
+	switch {
+	case strings.HasPrefix(region, "cn-"):
+		return PartitionChina
+	case strings.HasPrefix(region, "us-gov-"):
+		return PartitionGovCloud
+	}
+	return PartitionAWS
+}
+
+// pricingCurrency returns the currency AWS prices the region in.
+func pricingCurrency(region string) string {
+	if regionPartition(region) == PartitionChina {
+		return "CNY"
+	}
+	return "USD"
+}
+
+// CheckPricingAvailable returns an error explaining why the region can't be priced
+// when the pricing data of the selected service has no prices for it.
+func CheckPricingAvailable(region string) error {
+	available, err := pricingAvailable(Service, region)
+	if err != nil {
+		return err
+	}
+	if available {
+		return nil
+	}
+
+	name := serviceName(Service)
+	partition := regionPartition(region)
+	switch partition {
+	case PartitionChina:
+		return fmt.Errorf("the %s pricing data has no prices for %s: the %s partition is priced separately in %s by AWS China and isn't covered yet",
+			name, region, partition, pricingCurrency(region))
+	case PartitionGovCloud:
+		return fmt.Errorf("the %s pricing data has no prices for %s in the %s partition", name, region, partition)
+	}
+	return fmt.Errorf("the %s pricing data has no prices for %s, check the region name", name, region)
+}
+
+// pricingAvailable reports whether the pricing data of a service has prices for the region.
+func pricingAvailable(service, region string) (bool, error) {
+	switch service {
+	case ServiceEC2:
+		instanceTypes, err := ec2PricingData()
+		if err != nil {
+			return false, err
+		}
+		for _, instance := range *instanceTypes {
+			if _, ok := instance.Pricing[region]; ok {
+				return true, nil
+			}
+		}
+		return false, nil
+	case ServiceElastiCache:
+		nodeTypes, err := elastiCachePricingData()
+		if err != nil {
+			return false, err
+		}
+		for _, nodeType := range *nodeTypes {
+			if _, ok := nodeType.Pricing[region]; ok {
+				return true, nil
+			}
+		}
+		return false, nil
+	case ServiceOpenSearch:
+		instanceTypes, err := openSearchPricingData()
+		if err != nil {
+			return false, err
+		}
+		for _, instanceType := range *instanceTypes {
+			if _, ok := instanceType.Pricing[region]; ok {
+				return true, nil
+			}
+		}
+		return false, nil
+	case ServiceRedshift:
+		prices, err := redshiftPricingData(region)
+		if err != nil {
+			return false, err
+		}
+		return len(prices.Nodes) > 0, nil
+	case ServiceMemoryDB:
+		prices, err := memoryDBPricingData(region)
+		if err != nil {
+			return false, err
+		}
+		return len(prices.Redis) > 0 || len(prices.Valkey) > 0, nil
+	}
+
+	rdsData, err := ec2instancesinfo.RDSData()
+	if err != nil {
+		errorLog.Printf("Error fetching RDS data: %v", err)
+		return false, err
+	}
+	for _, instance := range *rdsData {
+		if _, ok := instance.Pricing[region]; ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"sort"
+	"time"
+)
+
+// PurchasePlan lists the reservations recommended for the inventory, in a format
+// meant to be reviewed and committed before purchasing them.
+type PurchasePlan struct {
+	GeneratedAt time.Time      `json:"generated_at"`
+	Region      string         `json:"region"`
+	Currency    string         `json:"currency"`
+	Term        string         `json:"term"`
+	Purchases   []PlanPurchase `json:"purchases"`
+	Totals      PlanTotals     `json:"totals"`
+}
+
+// PlanPurchase is a reservation to buy, with the databases justifying it.
+type PlanPurchase struct {
+	Service            string   `json:"service"`
+	InstanceType       string   `json:"instance_type"`
+	Engine             string   `json:"engine"`
+	ProductDescription string   `json:"product_description"`
+	DurationYears      int      `json:"duration_years"`
+	OfferingClass      string   `json:"offering_class"`
+	OfferingType       string   `json:"offering_type"`
+	Quantity           int      `json:"quantity"`
+	UpfrontCost        float64  `json:"expected_upfront_cost"`
+	MonthlyCost        float64  `json:"expected_monthly_cost"`
+	TotalCostForTerm   float64  `json:"expected_total_cost_for_term"`
+	OnDemandCost       float64  `json:"on_demand_cost_for_term"`
+	Savings            float64  `json:"expected_savings"`
+	SavingsPercent     float64  `json:"expected_savings_percent"`
+	Identifiers        []string `json:"identifiers"`
+}
+
+// PlanTotals sums the costs and savings of all the purchases of a plan.
+type PlanTotals struct {
+	UpfrontCost      float64 `json:"expected_upfront_cost"`
+	MonthlyCost      float64 `json:"expected_monthly_cost"`
+	TotalCostForTerm float64 `json:"expected_total_cost_for_term"`
+	OnDemandCost     float64 `json:"on_demand_cost_for_term"`
+	Savings          float64 `json:"expected_savings"`
+}
+
+// AWS names of the payment options.
+var offeringTypes = map[string]string{
+	"noUpfront":      "No Upfront",
+	"partialUpfront": "Partial Upfront",
+	"allUpfront":     "All Upfront",
+}
+
+// rdsProductDescription returns the product description of the RDS reserved
+// instance offerings of an engine.
+func rdsProductDescription(engine string) string {
+	switch engine {
+	case "MySQL":
+		return "mysql"
+	case "PostgreSQL":
+		return "postgresql"
+	case "Aurora MySQL":
+		return "aurora-mysql"
+	case "Aurora PostgreSQL":
+		return "aurora-postgresql"
+	case "MariaDB":
+		return "mariadb"
+	case EngineOracleSE2:
+		return "oracle-se2(li)"
+	case EngineOracleSE2BYOL:
+		return "oracle-se2(byol)"
+	case EngineOracleEEBYOL:
+		return "oracle-ee(byol)"
+	case EngineSQLServerEE:
+		return "sqlserver-ee(li)"
+	case EngineSQLServerSE:
+		return "sqlserver-se(li)"
+	case EngineSQLServerWeb:
+		return "sqlserver-web(li)"
+	case EngineSQLServerExpress:
+		return "sqlserver-ex(li)"
+		// Add cases for other database engines as needed
+	}
+	return ""
+}
+
+// BuildPurchasePlan recommends, for each instance type and engine, buying the
+// reserved option with the lowest cost for the term, provided it saves money over
+// on-demand. Multi-AZ instances count as two Single-AZ reservations.
+func BuildPurchasePlan(region string, data []PricingData, instances []InstanceInfo, term string) PurchasePlan {
+	plan := PurchasePlan{
+		GeneratedAt: time.Now().UTC().Truncate(time.Second),
+		Region:      region,
+		Currency:    SourceCurrency,
+		Term:        term,
+		Purchases:   []PlanPurchase{},
+	}
+
+	durationYears := 1
+	if term == "3 Year" {
+		durationYears = 3
+	}
+
+	enginesInUse := make(map[string]bool)
+	for _, instance := range instances {
+		enginesInUse[instance.Engine] = true
+	}
+
+	for engine := range enginesInUse {
+		if rdsProductDescription(engine) == "" {
+			continue // Only RDS reserved instances can be purchased from the plan
+		}
+		onDemand := make(map[string]PricingData)
+		best := make(map[string]PricingData)
+		for _, row := range AggregateCostsByTermAndEngine(data, instances, term, engine) {
+			if row.Term == "On-Demand" {
+				onDemand[row.InstanceType] = row
+			} else if row.OfferingStatus == OfferingStatusNotOffered {
+				continue // Can't be purchased
+			} else if current, ok := best[row.InstanceType]; !ok || row.TotalCostForTerm < current.TotalCostForTerm {
+				best[row.InstanceType] = row
+			}
+		}
+
+		for instanceType, row := range best {
+			onDemandRow, ok := onDemand[instanceType]
+			if !ok || row.TotalCostForTerm >= onDemandRow.TotalCostForTerm {
+				continue // Nothing to save by reserving
+			}
+
+			purchase := PlanPurchase{
+				Service:            "RDS",
+				InstanceType:       instanceType,
+				Engine:             engine,
+				ProductDescription: rdsProductDescription(engine),
+				DurationYears:      durationYears,
+				OfferingClass:      row.OfferingClass,
+				OfferingType:       offeringTypes[row.PaymentOption],
+				Quantity:           row.NumberOfInstances,
+				UpfrontCost:        roundCents(row.UpfrontCost * float64(row.NumberOfInstances)),
+				MonthlyCost:        roundCents(row.MonthlyCostPerInstance * float64(row.NumberOfInstances)),
+				TotalCostForTerm:   roundCents(row.TotalCostForTerm),
+				OnDemandCost:       roundCents(onDemandRow.TotalCostForTerm),
+				Identifiers:        row.Identifiers,
+			}
+			purchase.Savings = roundCents(purchase.OnDemandCost - purchase.TotalCostForTerm)
+			purchase.SavingsPercent = roundCents(purchase.Savings / purchase.OnDemandCost * 100)
+			plan.Purchases = append(plan.Purchases, purchase)
+
+			plan.Totals.UpfrontCost += purchase.UpfrontCost
+			plan.Totals.MonthlyCost += purchase.MonthlyCost
+			plan.Totals.TotalCostForTerm += purchase.TotalCostForTerm
+			plan.Totals.OnDemandCost += purchase.OnDemandCost
+			plan.Totals.Savings += purchase.Savings
+		}
+	}
+
+	plan.Totals = PlanTotals{
+		UpfrontCost:      roundCents(plan.Totals.UpfrontCost),
+		MonthlyCost:      roundCents(plan.Totals.MonthlyCost),
+		TotalCostForTerm: roundCents(plan.Totals.TotalCostForTerm),
+		OnDemandCost:     roundCents(plan.Totals.OnDemandCost),
+		Savings:          roundCents(plan.Totals.Savings),
+	}
+
+	sort.Slice(plan.Purchases, func(i, j int) bool {
+		if plan.Purchases[i].InstanceType != plan.Purchases[j].InstanceType {
+			return plan.Purchases[i].InstanceType < plan.Purchases[j].InstanceType
+		}
+		return plan.Purchases[i].Engine < plan.Purchases[j].Engine
+	})
+	return plan
+}
+
+// roundCents rounds an amount to the cent, keeping the plan readable.
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// WritePurchasePlan writes the plan as indented JSON, so it can be reviewed and diffed.
+func WritePurchasePlan(plan PurchasePlan, path string) error {
+	body, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(body, '\n'), 0o644)
+}
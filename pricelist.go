@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/pricing"
+	pricingtypes "github.com/aws/aws-sdk-go-v2/service/pricing/types"
+)
+
+// Payment options of the AWS Price List reserved terms.
+var priceListPurchaseOptions = map[string]string{
+	"No Upfront":      "noUpfront",
+	"Partial Upfront": "partialUpfront",
+	"All Upfront":     "allUpfront",
+}
+
+// priceListProduct is a product of the AWS Price List API with its terms, for the
+// services missing from the ec2-instances-info pricing data.
+type priceListProduct struct {
+	Product struct {
+		ProductFamily string            `json:"productFamily"`
+		Attributes    map[string]string `json:"attributes"`
+	} `json:"product"`
+	Terms struct {
+		OnDemand map[string]priceListTerm `json:"OnDemand"`
+		Reserved map[string]priceListTerm `json:"Reserved"`
+	} `json:"terms"`
+}
+
+type priceListTerm struct {
+	PriceDimensions map[string]struct {
+		Unit         string            `json:"unit"`
+		PricePerUnit map[string]string `json:"pricePerUnit"`
+	} `json:"priceDimensions"`
+	TermAttributes struct {
+		LeaseContractLength string `json:"LeaseContractLength"`
+		OfferingClass       string `json:"OfferingClass"`
+		PurchaseOption      string `json:"PurchaseOption"`
+	} `json:"termAttributes"`
+}
+
+// priceListInstancePricing holds the hourly prices of an instance type from the AWS
+// Price List API.
+type priceListInstancePricing struct {
+	OnDemand  float64
+	Reserved  map[string]float64 // Amortized hourly prices by reserved option
+	MemoryGiB float64
+}
+
+// priceListRegion returns the region serving the AWS Price List API of the
+// partition of a region.
+func priceListRegion(region string) string {
+	if regionPartition(region) == PartitionChina {
+		return "cn-northwest-1"
+	}
+	return "us-east-1"
+}
+
+// getPriceListProducts fetches the products of a service priced in the region,
+// narrowed down to the ones having the given attribute values.
+func getPriceListProducts(region, serviceCode string, attributes map[string]string) ([]priceListProduct, error) {
+	cfg, err := config.LoadDefaultConfig(context.TODO(), config.WithRegion(priceListRegion(region)))
+	if err != nil {
+		errorLog.Printf("Error loading AWS config: %v", err)
+		return nil, err
+	}
+	svc := pricing.NewFromConfig(cfg)
+
+	filters := []pricingtypes.Filter{{
+		Field: aws.String("regionCode"),
+		Type:  pricingtypes.FilterTypeTermMatch,
+		Value: aws.String(region),
+	}}
+	fields := make([]string, 0, len(attributes))
+	for field := range attributes {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		filters = append(filters, pricingtypes.Filter{
+			Field: aws.String(field),
+			Type:  pricingtypes.FilterTypeTermMatch,
+			Value: aws.String(attributes[field]),
+		})
+	}
+
+	var products []priceListProduct
+	paginator := pricing.NewGetProductsPaginator(svc, &pricing.GetProductsInput{
+		ServiceCode:   aws.String(serviceCode),
+		FormatVersion: aws.String("aws_v1"),
+		Filters:       filters,
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			errorLog.Printf("Error fetching the %s price list: %v", serviceCode, err)
+			return nil, err
+		}
+		for _, document := range page.PriceList {
+			var product priceListProduct
+			if err := json.Unmarshal([]byte(document), &product); err != nil {
+				errorLog.Printf("Error parsing the %s price list: %v", serviceCode, err)
+				return nil, err
+			}
+			products = append(products, product)
+		}
+	}
+
+	debugLog.Printf("Fetched %d %s products priced in %s", len(products), serviceCode, region)
+	return products, nil
+}
+
+// onDemandPrice returns the on-demand price per unit of the product, for the first
+// price dimension whose unit starts with the given one, such as Hrs.
+func (p priceListProduct) onDemandPrice(currency, unit string) float64 {
+	for _, term := range p.Terms.OnDemand {
+		for _, dimension := range term.PriceDimensions {
+			if strings.HasPrefix(strings.ToLower(dimension.Unit), strings.ToLower(unit)) {
+				price, _ := strconv.ParseFloat(dimension.PricePerUnit[currency], 64)
+				return price
+			}
+		}
+	}
+	return 0
+}
+
+// reservedPrices returns the amortized hourly prices of the reserved terms of the
+// product, keyed by reserved option such as yrTerm1Standard.allUpfront.
+func (p priceListProduct) reservedPrices(currency string) map[string]float64 {
+	prices := make(map[string]float64)
+	for _, term := range p.Terms.Reserved {
+		attributes := term.TermAttributes
+		years, err := strconv.Atoi(strings.TrimSuffix(attributes.LeaseContractLength, "yr"))
+		paymentOption, ok := priceListPurchaseOptions[attributes.PurchaseOption]
+		if err != nil || !ok {
+			debugLog.Printf("Skipping the %s %s reserved term", attributes.LeaseContractLength, attributes.PurchaseOption)
+			continue
+		}
+		offeringClass := "Standard"
+		if strings.EqualFold(attributes.OfferingClass, "convertible") {
+			offeringClass = "Convertible"
+		}
+
+		hourly := 0.0
+		for _, dimension := range term.PriceDimensions {
+			price, _ := strconv.ParseFloat(dimension.PricePerUnit[currency], 64)
+			if dimension.Unit == "Quantity" {
+				hourly += price / float64(years*hoursPerYear) // Upfront fee
+			} else {
+				hourly += price
+			}
+		}
+		prices[fmt.Sprintf("yrTerm%d%s.%s", years, offeringClass, paymentOption)] = hourly
+	}
+	return prices
+}
+
+// mentions reports whether one of the attributes of the product mentions the value,
+// ignoring the case, spaces and dashes, e.g. io-optimized in "IO Optimized".
+func (p priceListProduct) mentions(value string) bool {
+	normalize := strings.NewReplacer(" ", "", "-", "", "_", "")
+	value = normalize.Replace(strings.ToLower(value))
+	for _, attribute := range p.Product.Attributes {
+		if strings.Contains(normalize.Replace(strings.ToLower(attribute)), value) {
+			return true
+		}
+	}
+	return false
+}
+
+// priceListInstanceTypes returns the prices of the instance types of the accepted
+// products, keeping the cheapest one when several products price an instance type.
+func priceListInstanceTypes(products []priceListProduct, currency string, accept func(priceListProduct) bool) map[string]priceListInstancePricing {
+	instanceTypes := make(map[string]priceListInstancePricing)
+	for _, product := range products {
+		instanceType := product.Product.Attributes["instanceType"]
+		onDemand := product.onDemandPrice(currency, "Hrs")
+		if instanceType == "" || onDemand == 0 || !accept(product) {
+			continue
+		}
+		if current, ok := instanceTypes[instanceType]; ok && current.OnDemand <= onDemand {
+			continue
+		}
+		memory, _ := strconv.ParseFloat(strings.TrimSuffix(product.Product.Attributes["memory"], " GiB"), 64)
+		instanceTypes[instanceType] = priceListInstancePricing{
+			OnDemand:  onDemand,
+			Reserved:  product.reservedPrices(currency),
+			MemoryGiB: memory,
+		}
+	}
+	return instanceTypes
+}
+
+// priceListReservedOptions lists the standard reserved options of an instance type
+// priced by the AWS Price List API.
+func priceListReservedOptions(pricing priceListInstancePricing) []reservedOption {
+	var options []reservedOption
+	for _, term := range []string{
+		"yrTerm1Standard.noUpfront",
+		"yrTerm3Standard.noUpfront",
+		"yrTerm1Standard.partialUpfront",
+		"yrTerm3Standard.partialUpfront",
+		"yrTerm1Standard.allUpfront",
+		"yrTerm3Standard.allUpfront",
+	} {
+		options = append(options, reservedOption{term, pricing.Reserved[term]})
+	}
+	return options
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+	"github.com/aws/aws-sdk-go-v2/service/rds/types"
+	"github.com/olekukonko/tablewriter"
+)
+
+// PurchaseLogEntry records the outcome of a plan line, one JSON object per line of
+// the purchase log.
+type PurchaseLogEntry struct {
+	Time          time.Time `json:"time"`
+	ReservationID string    `json:"reservation_id"`
+	OfferingID    string    `json:"offering_id"`
+	InstanceType  string    `json:"instance_type"`
+	Quantity      int       `json:"quantity"`
+	UpfrontCost   float64   `json:"upfront_cost"`
+	Status        string    `json:"status"`
+	Error         string    `json:"error,omitempty"`
+}
+
+// resolvedPurchase is a plan line matched with the offering to buy.
+type resolvedPurchase struct {
+	Purchase      PlanPurchase
+	OfferingID    string
+	FixedPrice    float64
+	HourlyPrice   float64
+	ReservationID string
+	Status        string
+}
+
+// The upfront costs allowed by default, only the No Upfront reservations being
+// purchased unless a higher -max-upfront limit is given.
+const defaultMaxUpfront = 0.0
+
+// Purchase statuses.
+const (
+	PurchaseStatusPending   = "pending"
+	PurchaseStatusPurchased = "purchased"
+	PurchaseStatusSkipped   = "already purchased"
+	PurchaseStatusFailed    = "failed"
+)
+
+// newRDSClient returns an RDS client for the region, talking to the given endpoint
+// instead of the AWS one when set, such as a local fake RDS endpoint.
+func newRDSClient(region, endpoint string) (*rds.Client, error) {
+	cfg, err := config.LoadDefaultConfig(context.TODO(), config.WithRegion(region))
+	if err != nil {
+		errorLog.Printf("Error loading AWS config: %v", err)
+		return nil, err
+	}
+	return rds.NewFromConfig(cfg, func(o *rds.Options) {
+		if endpoint != "" {
+			o.BaseEndpoint = aws.String(endpoint)
+		}
+	}), nil
+}
+
+// RunPurchase implements the purchase subcommand, buying the reservations of an
+// approved plan file. Without -confirm it only shows what would be bought.
+func RunPurchase(args []string) {
+	flags := flag.NewFlagSet("purchase", flag.ExitOnError)
+	planFile := flags.String("plan", "", "Purchase plan file written with -plan-out")
+	confirm := flags.Bool("confirm", false, "Actually purchase the reservations, otherwise only show what would be bought")
+	maxUpfront := flags.Float64("max-upfront", defaultMaxUpfront, "Maximum total upfront cost of the purchases, refusing to buy anything above it (by default only No Upfront reservations can be bought)")
+	logFile := flags.String("log", "purchases.log", "File the purchases are appended to, also used to skip the ones already made")
+	endpoint := flags.String("endpoint-url", "", "RDS endpoint to use instead of the AWS one, such as a local fake RDS endpoint")
+	flags.Parse(args)
+
+	if *planFile == "" {
+		fmt.Println("Usage: script purchase -plan <file> [-confirm] [-max-upfront <amount>] [-log <file>] [-endpoint-url <url>]")
+		os.Exit(1)
+	}
+
+	if err := PurchasePlanFile(*planFile, *confirm, *maxUpfront, *logFile, *endpoint); err != nil {
+		fmt.Printf("Purchase failed: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// PurchasePlanFile resolves the lines of a plan to reserved offerings and purchases
+// them when confirmed, provided their total upfront cost doesn't exceed the maximum.
+func PurchasePlanFile(path string, confirm bool, maxUpfront float64, logPath, endpoint string) error {
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var plan PurchasePlan
+	if err := json.Unmarshal(body, &plan); err != nil {
+		return fmt.Errorf("parsing plan %s: %w", path, err)
+	}
+
+	svc, err := newRDSClient(plan.Region, endpoint)
+	if err != nil {
+		return err
+	}
+
+	purchased, err := readPurchaseLog(logPath)
+	if err != nil {
+		return err
+	}
+
+	var resolved []resolvedPurchase
+	totalUpfront := 0.0
+	for _, purchase := range plan.Purchases {
+		offering, err := findRDSOffering(svc, purchase)
+		if err != nil {
+			return err
+		}
+
+		line := resolvedPurchase{
+			Purchase:      purchase,
+			OfferingID:    aws.ToString(offering.ReservedDBInstancesOfferingId),
+			FixedPrice:    aws.ToFloat64(offering.FixedPrice),
+			HourlyPrice:   aws.ToFloat64(offering.UsagePrice),
+			ReservationID: reservationID(plan, purchase),
+			Status:        PurchaseStatusPending,
+		}
+		for _, charge := range offering.RecurringCharges {
+			line.HourlyPrice += aws.ToFloat64(charge.RecurringChargeAmount)
+		}
+		if purchased[line.ReservationID] {
+			line.Status = PurchaseStatusSkipped
+		} else {
+			totalUpfront += line.FixedPrice * float64(purchase.Quantity)
+		}
+		resolved = append(resolved, line)
+	}
+
+	printPurchases(resolved, confirm)
+	fmt.Printf("\nTotal upfront cost: %.2f (maximum %.2f)\n", totalUpfront, maxUpfront)
+
+	if totalUpfront > maxUpfront {
+		return fmt.Errorf("the total upfront cost of %.2f exceeds the -max-upfront limit of %.2f", totalUpfront, maxUpfront)
+	}
+	if !confirm {
+		fmt.Println("Dry run, nothing was purchased. Run again with -confirm to buy these reservations.")
+		return nil
+	}
+
+	logFile, err := os.OpenFile(logPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer logFile.Close()
+
+	failed := 0
+	for i, line := range resolved {
+		if line.Status == PurchaseStatusSkipped {
+			continue
+		}
+
+		entry := PurchaseLogEntry{
+			Time:          time.Now().UTC(),
+			ReservationID: line.ReservationID,
+			OfferingID:    line.OfferingID,
+			InstanceType:  line.Purchase.InstanceType,
+			Quantity:      line.Purchase.Quantity,
+			UpfrontCost:   line.FixedPrice * float64(line.Purchase.Quantity),
+			Status:        PurchaseStatusPurchased,
+		}
+
+		_, err := svc.PurchaseReservedDBInstancesOffering(context.TODO(), &rds.PurchaseReservedDBInstancesOfferingInput{
+			ReservedDBInstancesOfferingId: aws.String(line.OfferingID),
+			DBInstanceCount:               aws.Int32(int32(line.Purchase.Quantity)),
+			ReservedDBInstanceId:          aws.String(line.ReservationID),
+		})
+		var alreadyExists *types.ReservedDBInstanceAlreadyExistsFault
+		switch {
+		case errors.As(err, &alreadyExists):
+			entry.Status = PurchaseStatusSkipped // Bought by a previous run that couldn't log it
+		case err != nil:
+			errorLog.Printf("Error purchasing %s: %v", line.ReservationID, err)
+			entry.Status, entry.Error = PurchaseStatusFailed, err.Error()
+			failed++
+		}
+		resolved[i].Status = entry.Status
+
+		record, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		if _, err := logFile.Write(append(record, '\n')); err != nil {
+			return err
+		}
+	}
+
+	printPurchases(resolved, confirm)
+	if failed > 0 {
+		return fmt.Errorf("%d of the purchases failed, see %s", failed, logPath)
+	}
+	return nil
+}
+
+// findRDSOffering returns the reserved offering matching the attributes of a plan line.
+func findRDSOffering(svc *rds.Client, purchase PlanPurchase) (types.ReservedDBInstancesOffering, error) {
+	paginator := rds.NewDescribeReservedDBInstancesOfferingsPaginator(svc, &rds.DescribeReservedDBInstancesOfferingsInput{
+		DBInstanceClass:    aws.String(purchase.InstanceType),
+		Duration:           aws.String(fmt.Sprintf("%d", purchase.DurationYears)),
+		MultiAZ:            aws.Bool(false), // Multi-AZ databases are planned as two Single-AZ reservations
+		OfferingType:       aws.String(purchase.OfferingType),
+		ProductDescription: aws.String(purchase.ProductDescription),
+	})
+
+	var offerings []types.ReservedDBInstancesOffering
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			errorLog.Printf("Error describing the reserved offerings: %v", err)
+			return types.ReservedDBInstancesOffering{}, err
+		}
+		offerings = append(offerings, page.ReservedDBInstancesOfferings...)
+	}
+
+	description := fmt.Sprintf("%s %s %d year %s", purchase.InstanceType, purchase.ProductDescription, purchase.DurationYears, purchase.OfferingType)
+	switch len(offerings) {
+	case 0:
+		return types.ReservedDBInstancesOffering{}, fmt.Errorf("no reserved offering found for %s", description)
+	case 1:
+		return offerings[0], nil
+	}
+	return types.ReservedDBInstancesOffering{}, fmt.Errorf("%d reserved offerings match %s, expected one", len(offerings), description)
+}
+
+// reservationID derives the reservation identifier of a plan line from the plan and
+// the line, so purchasing the same plan twice doesn't buy its reservations twice.
+func reservationID(plan PurchasePlan, purchase PlanPurchase) string {
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%s|%s|%d|%s|%d",
+		plan.GeneratedAt.Format(time.RFC3339), plan.Region, purchase.InstanceType, purchase.ProductDescription,
+		purchase.DurationYears, purchase.OfferingType, purchase.Quantity)))
+	return fmt.Sprintf("plan-%x", hash[:8])
+}
+
+// readPurchaseLog returns the reservation identifiers already purchased according
+// to the purchase log, which may not exist yet.
+func readPurchaseLog(path string) (map[string]bool, error) {
+	purchased := make(map[string]bool)
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return purchased, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry PurchaseLogEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("parsing purchase log %s: %w", path, err)
+		}
+		if entry.Status == PurchaseStatusPurchased || entry.Status == PurchaseStatusSkipped {
+			purchased[entry.ReservationID] = true
+		}
+	}
+	return purchased, scanner.Err()
+}
+
+// printPurchases lists the resolved plan lines and their status.
+func printPurchases(purchases []resolvedPurchase, confirm bool) {
+	title := "\n## Reservations to Purchase (dry run)"
+	if confirm {
+		title = "\n## Reservations to Purchase"
+	}
+	fmt.Println(title)
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{
+		"Reservation ID",
+		"Instance Type",
+		"Product",
+		"Term",
+		"Offering Type",
+		"Quantity",
+		"Offering ID",
+		"Upfront Cost / instance",
+		"Hourly Cost / instance",
+		"Total Upfront Cost",
+		"Databases",
+		"Status",
+	})
+	table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	table.SetCenterSeparator("|")
+
+	for _, line := range purchases {
+		table.Append([]string{
+			line.ReservationID,
+			line.Purchase.InstanceType,
+			line.Purchase.ProductDescription,
+			fmt.Sprintf("%d Year", line.Purchase.DurationYears),
+			line.Purchase.OfferingType,
+			fmt.Sprintf("%d", line.Purchase.Quantity),
+			line.OfferingID,
+			fmt.Sprintf("%.2f", line.FixedPrice),
+			fmt.Sprintf("%.4f", line.HourlyPrice),
+			fmt.Sprintf("%.2f", line.FixedPrice*float64(line.Purchase.Quantity)),
+			strings.Join(line.Purchase.Identifiers, ", "),
+			line.Status,
+		})
+	}
+
+	table.Render()
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRDS is a local RDS endpoint serving one reserved offering per offering type
+// and recording the purchases.
+type fakeRDS struct {
+	mu        sync.Mutex
+	purchases []string          // Reservation IDs of the purchase calls
+	existing  map[string]bool   // Reservation IDs reported as already existing
+	prices    map[string]string // Fixed price by offering type
+}
+
+func newFakeRDS(t *testing.T) (*fakeRDS, *httptest.Server) {
+	fake := &fakeRDS{
+		existing: make(map[string]bool),
+		prices:   map[string]string{"No Upfront": "0.0", "All Upfront": "1000.0"},
+	}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	// Keep the credentials and configuration of the machine out of the tests
+	dir := t.TempDir()
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	t.Setenv("AWS_SESSION_TOKEN", "")
+	t.Setenv("AWS_PROFILE", "")
+	t.Setenv("AWS_ENDPOINT_URL", "")
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(dir, "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(dir, "credentials"))
+	return fake, server
+}
+
+func (f *fakeRDS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "text/xml")
+
+	switch r.Form.Get("Action") {
+	case "DescribeReservedDBInstancesOfferings":
+		offeringType := r.Form.Get("OfferingType")
+		fmt.Fprintf(w, `<DescribeReservedDBInstancesOfferingsResponse xmlns="http://rds.amazonaws.com/doc/2014-10-31/">
+<DescribeReservedDBInstancesOfferingsResult><ReservedDBInstancesOfferings><ReservedDBInstancesOffering>
+<ReservedDBInstancesOfferingId>offering-%s</ReservedDBInstancesOfferingId>
+<DBInstanceClass>%s</DBInstanceClass>
+<FixedPrice>%s</FixedPrice>
+<UsagePrice>0.0</UsagePrice>
+<OfferingType>%s</OfferingType>
+<MultiAZ>false</MultiAZ>
+<ProductDescription>%s</ProductDescription>
+<RecurringCharges/>
+</ReservedDBInstancesOffering></ReservedDBInstancesOfferings></DescribeReservedDBInstancesOfferingsResult>
+<ResponseMetadata><RequestId>1</RequestId></ResponseMetadata>
+</DescribeReservedDBInstancesOfferingsResponse>`,
+			r.Form.Get("DBInstanceClass"), r.Form.Get("DBInstanceClass"), f.prices[offeringType], offeringType, r.Form.Get("ProductDescription"))
+
+	case "PurchaseReservedDBInstancesOffering":
+		id := r.Form.Get("ReservedDBInstanceId")
+		f.mu.Lock()
+		f.purchases = append(f.purchases, id)
+		exists := f.existing[id]
+		f.existing[id] = true
+		f.mu.Unlock()
+
+		if exists {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `<ErrorResponse xmlns="http://rds.amazonaws.com/doc/2014-10-31/">
+<Error><Type>Sender</Type><Code>ReservedDBInstanceAlreadyExists</Code><Message>Reservation already exists</Message></Error>
+<RequestId>2</RequestId>
+</ErrorResponse>`)
+			return
+		}
+		fmt.Fprintf(w, `<PurchaseReservedDBInstancesOfferingResponse xmlns="http://rds.amazonaws.com/doc/2014-10-31/">
+<PurchaseReservedDBInstancesOfferingResult><ReservedDBInstance><ReservedDBInstanceId>%s</ReservedDBInstanceId></ReservedDBInstance></PurchaseReservedDBInstancesOfferingResult>
+<ResponseMetadata><RequestId>3</RequestId></ResponseMetadata>
+</PurchaseReservedDBInstancesOfferingResponse>`, id)
+
+	default:
+		http.Error(w, "unexpected action "+r.Form.Get("Action"), http.StatusBadRequest)
+	}
+}
+
+func (f *fakeRDS) purchaseCalls() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.purchases...)
+}
+
+// writePlan writes a plan of the given offering type lines to a temporary file.
+func writePlan(t *testing.T, offeringTypes ...string) (string, PurchasePlan) {
+	plan := PurchasePlan{
+		GeneratedAt: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		Region:      "us-east-1",
+		Currency:    SourceCurrency,
+		Term:        "1 Year",
+	}
+	for i, offeringType := range offeringTypes {
+		plan.Purchases = append(plan.Purchases, PlanPurchase{
+			Service:            "RDS",
+			InstanceType:       fmt.Sprintf("db.m5.%dxlarge", i+1),
+			Engine:             "PostgreSQL",
+			ProductDescription: "postgresql",
+			DurationYears:      1,
+			OfferingClass:      "Standard",
+			OfferingType:       offeringType,
+			Quantity:           2,
+			Identifiers:        []string{fmt.Sprintf("db-%d", i+1)},
+		})
+	}
+
+	body, err := json.Marshal(plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "plan.json")
+	if err := os.WriteFile(path, body, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path, plan
+}
+
+func readLogEntries(t *testing.T, path string) []PurchaseLogEntry {
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var entries []PurchaseLogEntry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry PurchaseLogEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("invalid purchase log line %q: %v", scanner.Text(), err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestPurchaseDryRunDoesNotPurchase(t *testing.T) {
+	fake, server := newFakeRDS(t)
+	planPath, _ := writePlan(t, "All Upfront", "No Upfront")
+	logPath := filepath.Join(t.TempDir(), "purchases.log")
+
+	if err := PurchasePlanFile(planPath, false, 5000, logPath, server.URL); err != nil {
+		t.Fatalf("dry run failed: %v", err)
+	}
+	if calls := fake.purchaseCalls(); len(calls) != 0 {
+		t.Errorf("dry run made purchase calls: %v", calls)
+	}
+	if _, err := os.Stat(logPath); !os.IsNotExist(err) {
+		t.Errorf("dry run wrote the purchase log: %v", err)
+	}
+}
+
+func TestPurchaseMaxUpfront(t *testing.T) {
+	tests := []struct {
+		name          string
+		offeringTypes []string
+		maxUpfront    float64
+		wantErr       bool
+	}{
+		{"default allows no upfront", []string{"No Upfront"}, defaultMaxUpfront, false},
+		{"default refuses upfront", []string{"All Upfront"}, defaultMaxUpfront, true},
+		{"total above the limit", []string{"All Upfront", "No Upfront"}, 1999, true},
+		{"total at the limit", []string{"All Upfront", "No Upfront"}, 2000, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fake, server := newFakeRDS(t)
+			planPath, _ := writePlan(t, test.offeringTypes...)
+			logPath := filepath.Join(t.TempDir(), "purchases.log")
+
+			err := PurchasePlanFile(planPath, true, test.maxUpfront, logPath, server.URL)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("got error %v, want error: %t", err, test.wantErr)
+			}
+			calls := fake.purchaseCalls()
+			if test.wantErr && len(calls) != 0 {
+				t.Errorf("purchased %v above the upfront limit", calls)
+			}
+			if !test.wantErr && len(calls) != len(test.offeringTypes) {
+				t.Errorf("got %d purchase calls, want %d", len(calls), len(test.offeringTypes))
+			}
+		})
+	}
+}
+
+func TestPurchaseIsIdempotent(t *testing.T) {
+	fake, server := newFakeRDS(t)
+	planPath, plan := writePlan(t, "All Upfront", "No Upfront")
+	logPath := filepath.Join(t.TempDir(), "purchases.log")
+
+	if err := PurchasePlanFile(planPath, true, 2000, logPath, server.URL); err != nil {
+		t.Fatalf("first purchase failed: %v", err)
+	}
+	want := []string{reservationID(plan, plan.Purchases[0]), reservationID(plan, plan.Purchases[1])}
+	if calls := fake.purchaseCalls(); len(calls) != 2 || calls[0] != want[0] || calls[1] != want[1] {
+		t.Fatalf("got purchases %v, want %v", calls, want)
+	}
+
+	// The purchase log skips the reservations already bought
+	if err := PurchasePlanFile(planPath, true, 2000, logPath, server.URL); err != nil {
+		t.Fatalf("second purchase failed: %v", err)
+	}
+	if calls := fake.purchaseCalls(); len(calls) != 2 {
+		t.Errorf("second run purchased again: %v", calls[2:])
+	}
+
+	// Without the log, RDS rejects the reservation IDs bought before
+	otherLogPath := filepath.Join(t.TempDir(), "purchases.log")
+	if err := PurchasePlanFile(planPath, true, 2000, otherLogPath, server.URL); err != nil {
+		t.Fatalf("purchase without the log failed: %v", err)
+	}
+	entries := readLogEntries(t, otherLogPath)
+	if len(entries) != len(plan.Purchases) {
+		t.Fatalf("got %d log entries, want %d", len(entries), len(plan.Purchases))
+	}
+	for _, entry := range entries {
+		if entry.Status != PurchaseStatusSkipped {
+			t.Errorf("%s: got status %q, want %q", entry.ReservationID, entry.Status, PurchaseStatusSkipped)
+		}
+	}
+	if reservationID(plan, plan.Purchases[0]) != want[0] {
+		t.Error("the reservation IDs aren't stable")
+	}
+}
+
+func TestPurchaseLogRecords(t *testing.T) {
+	_, server := newFakeRDS(t)
+	planPath, plan := writePlan(t, "All Upfront", "No Upfront")
+	logPath := filepath.Join(t.TempDir(), "purchases.log")
+
+	if err := PurchasePlanFile(planPath, true, 2000, logPath, server.URL); err != nil {
+		t.Fatalf("purchase failed: %v", err)
+	}
+
+	entries := readLogEntries(t, logPath)
+	if len(entries) != len(plan.Purchases) {
+		t.Fatalf("got %d log entries, want %d", len(entries), len(plan.Purchases))
+	}
+	wantUpfront := []float64{2000, 0}
+	for i, entry := range entries {
+		purchase := plan.Purchases[i]
+		if entry.ReservationID != reservationID(plan, purchase) ||
+			entry.OfferingID != "offering-"+purchase.InstanceType ||
+			entry.InstanceType != purchase.InstanceType ||
+			entry.Quantity != purchase.Quantity ||
+			entry.UpfrontCost != wantUpfront[i] ||
+			entry.Status != PurchaseStatusPurchased ||
+			entry.Time.IsZero() {
+			t.Errorf("unexpected log entry %+v for %+v", entry, purchase)
+		}
+	}
+}
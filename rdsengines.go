@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+)
+
+// Engines described by DescribeDBInstances along with the RDS ones, but missing
+// from the RDS pricing data, or whose prices there don't tell their editions and
+// license models apart.
+const (
+	EngineDocumentDB       = "DocumentDB"
+	EngineNeptune          = "Neptune"
+	EngineOracleSE2        = "Oracle SE2"
+	EngineOracleSE2BYOL    = "Oracle SE2 BYOL"
+	EngineOracleEEBYOL     = "Oracle EE BYOL"
+	EngineSQLServerEE      = "SQL Server EE"
+	EngineSQLServerSE      = "SQL Server SE"
+	EngineSQLServerWeb     = "SQL Server Web"
+	EngineSQLServerExpress = "SQL Server Express"
+)
+
+// The RDS license model of the instances bringing their own Oracle license.
+const licenseModelBYOL = "bring-your-own-license"
+
+// priceListEngine locates the prices of an engine in the AWS Price List API.
+type priceListEngine struct {
+	ServiceCode string
+	Attributes  map[string]string // Product attributes of the engine's instances
+	Service     string            // Service of the discounts applying to the engine
+}
+
+// rdsPriceListEngine locates the Single-AZ instance prices of an RDS engine edition
+// and license model, a Multi-AZ instance counting as two of them.
+func rdsPriceListEngine(engine, edition, licenseModel string) priceListEngine {
+	return priceListEngine{
+		ServiceCode: "AmazonRDS",
+		Attributes: map[string]string{
+			"databaseEngine":   engine,
+			"databaseEdition":  edition,
+			"licenseModel":     licenseModel,
+			"deploymentOption": "Single-AZ",
+			"locationType":     "AWS Region",
+		},
+		Service: serviceName(ServiceRDS),
+	}
+}
+
+// Engines priced from the AWS Price List API.
+var priceListEngines = map[string]priceListEngine{
+	EngineDocumentDB:       {ServiceCode: "AmazonDocDB", Service: EngineDocumentDB},
+	EngineNeptune:          {ServiceCode: "AmazonNeptune", Service: EngineNeptune},
+	EngineOracleSE2:        rdsPriceListEngine("Oracle", "Standard Two", "License included"),
+	EngineOracleSE2BYOL:    rdsPriceListEngine("Oracle", "Standard Two", "Bring your own license"),
+	EngineOracleEEBYOL:     rdsPriceListEngine("Oracle", "Enterprise", "Bring your own license"),
+	EngineSQLServerEE:      rdsPriceListEngine("SQL Server", "Enterprise", "License included"),
+	EngineSQLServerSE:      rdsPriceListEngine("SQL Server", "Standard", "License included"),
+	EngineSQLServerWeb:     rdsPriceListEngine("SQL Server", "Web", "License included"),
+	EngineSQLServerExpress: rdsPriceListEngine("SQL Server", "Express", "License included"),
+}
+
+// rdsEngine returns the engine an instance is priced for from its RDS API engine
+// name and license model. Only Oracle SE2 is sold with both license models, the
+// instances whose license model isn't known being priced as license included.
+func rdsEngine(engine, licenseModel string) string {
+	name := determineServiceFromDBEngine(&engine)
+	if name == EngineOracleSE2 && licenseModel == licenseModelBYOL {
+		return EngineOracleSE2BYOL
+	}
+	return name
+}
+
+// The prices of these engines are only fetched once per engine and region.
+var priceListEngineData = make(map[string]map[string]priceListInstancePricing)
+
+// priceListEnginePricingData returns the instance prices of an engine priced from
+// the AWS Price List API. Clusters using the I/O-Optimized storage pay higher
+// instance prices, the standard storage ones are used.
+func priceListEnginePricingData(region, engine string) (map[string]priceListInstancePricing, error) {
+	key := engine + "-" + region
+	if data, ok := priceListEngineData[key]; ok {
+		return data, nil
+	}
+	products, err := getPriceListProducts(region, priceListEngines[engine].ServiceCode, priceListEngines[engine].Attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	data := priceListInstanceTypes(products, pricingCurrency(region), func(product priceListProduct) bool {
+		return !product.mentions("iooptimized")
+	})
+	priceListEngineData[key] = data
+	return data, nil
+}
+
+// ProcessPriceListEnginePricingData returns the on-demand and reserved pricing rows
+// of each instance type and engine of the aggregated instances whose engine is
+// priced from the AWS Price List API, for both terms. The DocumentDB and Neptune
+// discounts are configured by engine, the Oracle and SQL Server ones being the RDS
+// discounts.
+func ProcessPriceListEnginePricingData(region string, runningInstances []InstanceInfo) ([]PricingData, []PricingData) {
+	processed := make(map[string]bool)
+	var finalData1Year, finalData3Years []PricingData
+	for _, instance := range runningInstances {
+		key := fmt.Sprintf("%s-%s", instance.InstanceType, instance.Engine)
+		if processed[key] {
+			continue
+		}
+		processed[key] = true
+
+		prices, err := priceListEnginePricingData(region, instance.Engine)
+		if err != nil {
+			errorLog.Printf("Failed to fetch %s data: %v", instance.Engine, err)
+			continue
+		}
+		pricing := prices[instance.InstanceType]
+		if pricing.OnDemand == 0 {
+			debugLog.Printf("No %s pricing for %s in %s", instance.Engine, instance.InstanceType, region)
+			continue
+		}
+
+		service := priceListEngines[instance.Engine].Service
+		discount := discountFactor(service, region)
+		onDemand1Year, onDemand3Years := onDemandRows(instance.InstanceType, region, pricing.OnDemand*discount, instance.NumberOfInstances)
+		reserved1Year, reserved3Years := reservedRows(instance.InstanceType, priceListReservedOptions(pricing), pricing.OnDemand, discount, instance.NumberOfInstances)
+		data1Year := append([]PricingData{onDemand1Year}, reserved1Year...)
+		data3Years := append([]PricingData{onDemand3Years}, reserved3Years...)
+
+		for _, data := range [][]PricingData{data1Year, data3Years} {
+			for i := range data {
+				data[i].Engine = instance.Engine
+				data[i].DiscountPercent = Discounts.DiscountPercent(service, region)
+			}
+		}
+		finalData1Year = append(finalData1Year, data1Year...)
+		finalData3Years = append(finalData3Years, data3Years...)
+	}
+
+	debugLog.Printf("Price List engines Data 1 year: %v", finalData1Year)
+	debugLog.Printf("Price List engines Data 3 years: %v", finalData3Years)
+	return finalData1Year, finalData3Years
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/redshift"
+	redshifttypes "github.com/aws/aws-sdk-go-v2/service/redshift/types"
+	"github.com/olekukonko/tablewriter"
+)
+
+const EngineRedshift = "Redshift"
+
+// Redshift Serverless capacity is set in RPUs of 16 GiB of memory, the base
+// capacity being a multiple of 8 RPUs.
+const (
+	redshiftRPUMemoryGiB = 16
+	redshiftRPUStep      = 8
+)
+
+// redshiftPricing holds the Redshift prices of a region, which aren't part of the
+// ec2-instances-info pricing data and are fetched from the AWS Price List API.
+type redshiftPricing struct {
+	Nodes     map[string]priceListInstancePricing
+	RPUHourly float64 // Price of a Redshift Serverless RPU hour
+}
+
+// The Redshift prices are only fetched once per region.
+var redshiftData = make(map[string]*redshiftPricing)
+
+// redshiftPricingData returns the Redshift node type and Serverless prices of the region.
+func redshiftPricingData(region string) (*redshiftPricing, error) {
+	if data, ok := redshiftData[region]; ok {
+		return data, nil
+	}
+	products, err := getPriceListProducts(region, "AmazonRedshift", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	currency := pricingCurrency(region)
+	data := &redshiftPricing{
+		Nodes: priceListInstanceTypes(products, currency, func(product priceListProduct) bool {
+			return product.Product.ProductFamily == "Compute Instance"
+		}),
+	}
+	for _, product := range products {
+		// Serverless compute is the only usage billed per RPU hour
+		if price := product.onDemandPrice(currency, "RPU"); price > 0 && (data.RPUHourly == 0 || price < data.RPUHourly) {
+			data.RPUHourly = price
+		}
+	}
+
+	redshiftData[region] = data
+	return data, nil
+}
+
+// GetRunningRedshiftClusters fetches the provisioned Redshift clusters whose status
+// is one of the given statuses, each entry counting its nodes. The others are
+// returned as excluded instances.
+func GetRunningRedshiftClusters(region string, statuses []string) ([]InstanceInfo, []ExcludedInstance, error) {
+	cfg, err := config.LoadDefaultConfig(context.TODO(), config.WithRegion(region))
+	if err != nil {
+		errorLog.Printf("Error loading AWS config: %v", err)
+		return nil, nil, err
+	}
+
+	svc := redshift.NewFromConfig(cfg)
+	paginator := redshift.NewDescribeClustersPaginator(svc, &redshift.DescribeClustersInput{})
+
+	var instances []InstanceInfo
+	var excluded []ExcludedInstance
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			errorLog.Printf("Error describing Redshift clusters: %v", err)
+			return nil, nil, err
+		}
+
+		for _, cluster := range page.Clusters {
+			info := redshiftClusterInfo(region, cluster)
+			if !isStatusIncluded(info.Status, statuses) {
+				excluded = append(excluded, ExcludedInstance{
+					Identifier:   info.Identifier,
+					InstanceType: info.InstanceType,
+					Engine:       info.Engine,
+					Status:       info.Status,
+					Reason:       "Status not included by the status policy",
+				})
+				continue
+			}
+			instances = append(instances, info)
+		}
+	}
+
+	debugLog.Printf("Found running Redshift clusters: %v", instances)
+	debugLog.Printf("Excluded Redshift clusters: %v", excluded)
+	return instances, excluded, nil
+}
+
+// redshiftClusterInfo describes a cluster, Multi-AZ clusters running their nodes
+// in two availability zones.
+func redshiftClusterInfo(region string, cluster redshifttypes.Cluster) InstanceInfo {
+	multiAZ := strings.EqualFold(aws.ToString(cluster.MultiAZ), "Enabled")
+	nodes := int(aws.ToInt32(cluster.NumberOfNodes))
+	if multiAZ {
+		nodes *= 2
+	}
+
+	// The account is only returned as part of the namespace ARN
+	account := ""
+	if parts := strings.Split(aws.ToString(cluster.ClusterNamespaceArn), ":"); len(parts) > 4 {
+		account = parts[4]
+	}
+
+	return InstanceInfo{
+		InstanceType:      aws.ToString(cluster.NodeType),
+		NumberOfInstances: nodes,
+		Engine:            EngineRedshift,
+		MultiAZ:           multiAZ,
+		Tags:              tagsFromRedshiftTags(cluster.Tags),
+		Status:            aws.ToString(cluster.ClusterStatus),
+		Identifier:        aws.ToString(cluster.ClusterIdentifier),
+		ARN: fmt.Sprintf("arn:%s:redshift:%s:%s:cluster:%s",
+			regionPartition(region), region, account, aws.ToString(cluster.ClusterIdentifier)),
+		EngineVersion:    aws.ToString(cluster.ClusterVersion),
+		AvailabilityZone: aws.ToString(cluster.AvailabilityZone),
+		CreationTime:     aws.ToTime(cluster.ClusterCreateTime),
+	}
+}
+
+// ProcessRedshiftPricingData returns the on-demand and reserved pricing rows of
+// each node type of the aggregated Redshift clusters, for both terms.
+func ProcessRedshiftPricingData(region string, runningInstances []InstanceInfo) ([]PricingData, []PricingData) {
+	prices, err := redshiftPricingData(region)
+	if err != nil {
+		errorLog.Printf("Failed to fetch Redshift data: %v", err)
+		return nil, nil
+	}
+
+	discount := discountFactor(serviceName(ServiceRedshift), region)
+	discountPercent := Discounts.DiscountPercent(serviceName(ServiceRedshift), region)
+
+	processed := make(map[string]bool)
+	var finalData1Year, finalData3Years []PricingData
+	for _, instance := range runningInstances {
+		key := fmt.Sprintf("%s-%s", instance.InstanceType, instance.Engine)
+		if processed[key] {
+			continue
+		}
+		processed[key] = true
+
+		pricing := prices.Nodes[instance.InstanceType]
+		if pricing.OnDemand == 0 {
+			debugLog.Printf("No Redshift pricing for %s in %s", instance.InstanceType, region)
+			continue
+		}
+
+		onDemand1Year, onDemand3Years := onDemandRows(instance.InstanceType, region, pricing.OnDemand*discount, instance.NumberOfInstances)
+		reserved1Year, reserved3Years := reservedRows(instance.InstanceType, priceListReservedOptions(pricing), pricing.OnDemand, discount, instance.NumberOfInstances)
+		data1Year := append([]PricingData{onDemand1Year}, reserved1Year...)
+		data3Years := append([]PricingData{onDemand3Years}, reserved3Years...)
+
+		for _, data := range [][]PricingData{data1Year, data3Years} {
+			for i := range data {
+				data[i].Engine = instance.Engine
+				data[i].DiscountPercent = discountPercent
+			}
+		}
+		finalData1Year = append(finalData1Year, data1Year...)
+		finalData3Years = append(finalData3Years, data3Years...)
+	}
+
+	debugLog.Printf("Redshift Data 1 year: %v", finalData1Year)
+	debugLog.Printf("Redshift Data 3 years: %v", finalData3Years)
+	return finalData1Year, finalData3Years
+}
+
+// ServerlessComparison compares the on-demand cost of a provisioned RA3 cluster
+// with a Redshift Serverless workgroup of the same memory.
+type ServerlessComparison struct {
+	Identifier          string
+	NodeType            string
+	Nodes               int
+	ProvisionedMonthly  float64
+	RPUs                int
+	ServerlessMonthly   float64 // Cost of the base capacity running around the clock
+	BreakEvenDailyHours float64 // Daily hours of queries below which Serverless costs less
+}
+
+// CompareRedshiftServerless sizes a Serverless workgroup for each RA3 cluster and
+// computes how busy the cluster can be for Serverless to still cost less, as
+// Serverless is only billed while queries run.
+func CompareRedshiftServerless(region string, instances []InstanceInfo) ([]ServerlessComparison, error) {
+	prices, err := redshiftPricingData(region)
+	if err != nil {
+		return nil, err
+	}
+	if prices.RPUHourly == 0 {
+		debugLog.Printf("No Redshift Serverless pricing in %s", region)
+		return nil, nil
+	}
+	discount := discountFactor(serviceName(ServiceRedshift), region)
+
+	var comparisons []ServerlessComparison
+	for _, instance := range instances {
+		pricing := prices.Nodes[instance.InstanceType]
+		if !strings.HasPrefix(instance.InstanceType, "ra3.") || pricing.OnDemand == 0 || pricing.MemoryGiB == 0 {
+			continue
+		}
+
+		memory := pricing.MemoryGiB * float64(instance.NumberOfInstances)
+		rpus := int(math.Ceil(memory/redshiftRPUMemoryGiB/redshiftRPUStep)) * redshiftRPUStep
+		provisionedHourly := pricing.OnDemand * float64(instance.NumberOfInstances) * discount
+		serverlessHourly := prices.RPUHourly * float64(rpus) * discount
+
+		comparisons = append(comparisons, ServerlessComparison{
+			Identifier:          instance.Identifier,
+			NodeType:            instance.InstanceType,
+			Nodes:               instance.NumberOfInstances,
+			ProvisionedMonthly:  provisionedHourly * monthlyHours(1),
+			RPUs:                rpus,
+			ServerlessMonthly:   serverlessHourly * monthlyHours(1),
+			BreakEvenDailyHours: math.Min(24*provisionedHourly/serverlessHourly, 24),
+		})
+	}
+
+	sort.Slice(comparisons, func(i, j int) bool {
+		return comparisons[i].Identifier < comparisons[j].Identifier
+	})
+	return comparisons, nil
+}
+
+// PrintRedshiftServerless prints the RA3 clusters Redshift Serverless might cost
+// less for, along with the daily hours of queries it breaks even at.
+func PrintRedshiftServerless(comparisons []ServerlessComparison) {
+	if len(comparisons) == 0 {
+		return
+	}
+
+	fmt.Println("\n## Redshift Serverless Candidates")
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader(currencyHeaders([]string{
+		"Cluster",
+		"Node Type",
+		"Nodes",
+		"On-Demand Monthly Cost ($)",
+		"Serverless RPUs",
+		"Serverless Monthly Cost Around The Clock ($)",
+		"Serverless Cheaper Below (Hours/Day)",
+	}))
+	table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	table.SetCenterSeparator("|")
+
+	for _, comparison := range comparisons {
+		breakEven := fmt.Sprintf("%.1f", comparison.BreakEvenDailyHours)
+		if comparison.BreakEvenDailyHours >= 24 {
+			breakEven = "24 (always cheaper)"
+		}
+		table.Append([]string{
+			comparison.Identifier,
+			comparison.NodeType,
+			fmt.Sprintf("%d", comparison.Nodes),
+			formatCost(comparison.ProvisionedMonthly),
+			fmt.Sprintf("%d", comparison.RPUs),
+			formatCost(comparison.ServerlessMonthly),
+			breakEven,
+		})
+	}
+
+	table.Render()
+}
+
+func tagsFromRedshiftTags(tagList []redshifttypes.Tag) map[string]string {
+	tags := make(map[string]string, len(tagList))
+	for _, tag := range tagList {
+		tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	return tags
+}
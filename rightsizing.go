@@ -0,0 +1,278 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	ec2instancesinfo "github.com/LeanerCloud/ec2-instances-info"
+	"github.com/olekukonko/tablewriter"
+)
+
+const (
+	rightsizingTargetCPUPercent    = 70.0 // Peak CPU the suggested class should stay under
+	rightsizingMemoryHeadroom      = 0.8  // Share of the suggested class memory the workload may use
+	rightsizingConnectionsHeadroom = 1.2  // Margin over the peak connections the suggested class must support
+	bytesInGiB                     = 1024 * 1024 * 1024
+)
+
+// RightsizingSuggestion holds the utilization of a database and the class suggested for it.
+type RightsizingSuggestion struct {
+	Identifier            string
+	Engine                string
+	InstanceType          string
+	SuggestedInstanceType string
+	NumberOfInstances     int
+	PeakCPUPercent        float64
+	ProjectedCPUPercent   float64
+	MinFreeableMemoryGiB  float64
+	PeakConnections       float64
+}
+
+// RightsizingReport holds the suggestions for each database, by identifier, and the
+// pricing of the inventory once all the suggestions are applied.
+type RightsizingReport struct {
+	Suggestions map[string]RightsizingSuggestion
+	OneYear     []PricingData
+	ThreeYear   []PricingData
+}
+
+// GetRightsizingReport suggests a smaller class for the databases whose CPU, memory and
+// connections peaks over the last days would fit it, and prices the resulting inventory.
+func GetRightsizingReport(region string, instances []InstanceInfo, days int) (*RightsizingReport, error) {
+	rdsData, err := ec2instancesinfo.RDSData()
+	if err != nil {
+		errorLog.Printf("Error fetching RDS data: %v", err)
+		return nil, err
+	}
+
+	var metrics []rdsMetricQuery
+	for _, instance := range instances {
+		metrics = append(metrics,
+			rdsMetricQuery{Identifier: instance.Identifier, MetricName: "CPUUtilization", Stat: "Average"},
+			rdsMetricQuery{Identifier: instance.Identifier, MetricName: "FreeableMemory", Stat: "Minimum"},
+			rdsMetricQuery{Identifier: instance.Identifier, MetricName: "DatabaseConnections", Stat: "Maximum"},
+		)
+	}
+
+	end := time.Now().Truncate(time.Hour)
+	values, err := getRDSMetricValues(region, metrics, end.AddDate(0, 0, -days), end)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &RightsizingReport{Suggestions: make(map[string]RightsizingSuggestion)}
+	var suggestedInstances []InstanceInfo
+	for _, instance := range instances {
+		suggestion := RightsizingSuggestion{
+			Identifier:            instance.Identifier,
+			Engine:                instance.Engine,
+			InstanceType:          instance.InstanceType,
+			SuggestedInstanceType: instance.InstanceType,
+			NumberOfInstances:     instance.NumberOfInstances,
+		}
+
+		cpu := values[rdsMetricQuery{Identifier: instance.Identifier, MetricName: "CPUUtilization", Stat: "Average"}]
+		memory := values[rdsMetricQuery{Identifier: instance.Identifier, MetricName: "FreeableMemory", Stat: "Minimum"}]
+		connections := values[rdsMetricQuery{Identifier: instance.Identifier, MetricName: "DatabaseConnections", Stat: "Maximum"}]
+
+		if len(cpu) == 0 || len(memory) == 0 {
+			debugLog.Printf("No CloudWatch metrics for %s, keeping its instance type", instance.Identifier)
+		} else {
+			suggestion.PeakCPUPercent = maxValue(cpu)
+			suggestion.MinFreeableMemoryGiB = minValue(memory) / bytesInGiB
+			suggestion.PeakConnections = maxValue(connections)
+			suggestion.SuggestedInstanceType, suggestion.ProjectedCPUPercent = suggestInstanceType(*rdsData, region, suggestion)
+		}
+
+		report.Suggestions[instance.Identifier] = suggestion
+
+		suggested := instance
+		suggested.InstanceType = suggestion.SuggestedInstanceType
+		suggestedInstances = append(suggestedInstances, suggested)
+	}
+
+	debugLog.Printf("Rightsizing suggestions: %v", report.Suggestions)
+
+	report.OneYear, report.ThreeYear = ProcessPricingData(region, aggregateInstances(suggestedInstances))
+	return report, nil
+}
+
+// suggestInstanceType returns the cheapest class of the same family, or of its
+// Graviton equivalent families for the x86 classes, fitting the peak utilization of
+// the database, and the CPU utilization projected on that class.
+func suggestInstanceType(rdsData ec2instancesinfo.RDSInstanceData, region string, suggestion RightsizingSuggestion) (string, float64) {
+	current := findRDSInstanceType(rdsData, suggestion.InstanceType)
+	if current == nil || current.Vcpu == 0 {
+		return suggestion.InstanceType, suggestion.PeakCPUPercent
+	}
+
+	usedMemoryGiB := float64(current.Memory) - suggestion.MinFreeableMemoryGiB
+	bestType, bestCPU := suggestion.InstanceType, suggestion.PeakCPUPercent
+	bestPrice := rdsEnginePricing(current.Pricing[region], suggestion.Engine).OnDemand
+
+	families := map[string]bool{rdsInstanceFamily(suggestion.InstanceType): true}
+	if !isGraviton(*current) {
+		for _, family := range gravitonFamilies[instanceClassLetter(suggestion.InstanceType)] {
+			families[family] = true
+		}
+	}
+
+	for _, candidate := range rdsData {
+		if !families[rdsInstanceFamily(candidate.InstanceType)] || candidate.Vcpu == 0 {
+			continue
+		}
+
+		price := rdsEnginePricing(candidate.Pricing[region], suggestion.Engine).OnDemand
+		if price == 0 || price >= bestPrice {
+			continue // Only consider cheaper classes available for the engine
+		}
+
+		projectedCPU := suggestion.PeakCPUPercent * float64(current.Vcpu) / float64(candidate.Vcpu)
+		if projectedCPU > rightsizingTargetCPUPercent ||
+			usedMemoryGiB > float64(candidate.Memory)*rightsizingMemoryHeadroom ||
+			estimatedMaxConnections(suggestion.Engine, candidate.Memory) < suggestion.PeakConnections*rightsizingConnectionsHeadroom {
+			continue
+		}
+
+		bestType, bestCPU, bestPrice = candidate.InstanceType, projectedCPU, price
+	}
+	return bestType, bestCPU
+}
+
+func findRDSInstanceType(rdsData ec2instancesinfo.RDSInstanceData, instanceType string) *ec2instancesinfo.RDSInstance {
+	for i := range rdsData {
+		if rdsData[i].InstanceType == instanceType {
+			return &rdsData[i]
+		}
+	}
+	return nil
+}
+
+// rdsInstanceFamily returns the family of an instance class, such as db.m5 for db.m5.xlarge.
+func rdsInstanceFamily(instanceType string) string {
+	if i := strings.LastIndex(instanceType, "."); i > 0 {
+		return instanceType[:i]
+	}
+	return instanceType
+}
+
+// estimatedMaxConnections estimates the default max_connections of an engine for the
+// memory of a class, based on the formulas of the default RDS parameter groups.
+func estimatedMaxConnections(engine string, memoryGiB float32) float64 {
+	memoryBytes := float64(memoryGiB) * bytesInGiB
+	switch engine {
+	case "MySQL":
+		return memoryBytes / 12582880
+	case "PostgreSQL", "Aurora PostgreSQL":
+		return math.Min(memoryBytes/9531392, 5000)
+	}
+	return math.Inf(1)
+}
+
+func maxValue(values []float64) float64 {
+	result := 0.0
+	for _, value := range values {
+		result = math.Max(result, value)
+	}
+	return result
+}
+
+func minValue(values []float64) float64 {
+	result := math.Inf(1)
+	for _, value := range values {
+		result = math.Min(result, value)
+	}
+	return result
+}
+
+// ApplyToPricingData fills in the suggested class columns of the aggregated pricing rows of a term.
+func (r *RightsizingReport) ApplyToPricingData(rows []PricingData, term string) []PricingData {
+	suggestedData := r.OneYear
+	if term == "3 Year" {
+		suggestedData = r.ThreeYear
+	}
+
+	costs := make(map[string]float64)
+	for _, row := range suggestedData {
+		key := fmt.Sprintf("%s-%s-%s-%s-%s", row.InstanceType, row.Engine, row.Term, row.OfferingClass, row.PaymentOption)
+		costs[key] = row.CostForTermPerInstance
+	}
+
+	for i, row := range rows {
+		suggestedTypes := make(map[string]bool)
+		total, complete := 0.0, len(row.Identifiers) > 0
+		for _, identifier := range row.Identifiers {
+			suggestion, ok := r.Suggestions[identifier]
+			if !ok {
+				complete = false
+				break
+			}
+			key := fmt.Sprintf("%s-%s-%s-%s-%s", suggestion.SuggestedInstanceType, row.Engine, row.Term, row.OfferingClass, row.PaymentOption)
+			cost, ok := costs[key]
+			if !ok {
+				complete = false // The option isn't available for the suggested class
+				break
+			}
+			total += cost * float64(suggestion.NumberOfInstances)
+			suggestedTypes[suggestion.SuggestedInstanceType] = true
+		}
+		if !complete {
+			continue
+		}
+
+		var types []string
+		for instanceType := range suggestedTypes {
+			types = append(types, instanceType)
+		}
+		sort.Strings(types)
+
+		rows[i].SuggestedInstanceType = strings.Join(types, ", ")
+		rows[i].SuggestedTotalCostForTerm = total
+		rows[i].RightsizingSavings = row.TotalCostForTerm - total
+	}
+	return rows
+}
+
+// PrintRightsizingSuggestions lists the databases for which a smaller class is suggested.
+func PrintRightsizingSuggestions(report *RightsizingReport) {
+	var identifiers []string
+	for identifier, suggestion := range report.Suggestions {
+		if suggestion.SuggestedInstanceType != suggestion.InstanceType {
+			identifiers = append(identifiers, identifier)
+		}
+	}
+	sort.Strings(identifiers)
+
+	fmt.Println("\n## Rightsizing Suggestions")
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{
+		"Identifier",
+		"Instance Type",
+		"Suggested Instance Type",
+		"Peak CPU (%)",
+		"Projected Peak CPU (%)",
+		"Min Freeable Memory (GiB)",
+		"Peak Connections",
+	})
+	table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	table.SetCenterSeparator("|")
+
+	for _, identifier := range identifiers {
+		suggestion := report.Suggestions[identifier]
+		table.Append([]string{
+			suggestion.Identifier,
+			suggestion.InstanceType,
+			suggestion.SuggestedInstanceType,
+			fmt.Sprintf("%.2f", suggestion.PeakCPUPercent),
+			fmt.Sprintf("%.2f", suggestion.ProjectedCPUPercent),
+			fmt.Sprintf("%.2f", suggestion.MinFreeableMemoryGiB),
+			fmt.Sprintf("%.0f", suggestion.PeakConnections),
+		})
+	}
+
+	table.Render()
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"testing"
+
+	ec2instancesinfo "github.com/LeanerCloud/ec2-instances-info"
+)
+
+// rdsClass returns an instance class priced for PostgreSQL in us-east-1.
+func rdsClass(instanceType, processor string, vcpu int32, memory float32, onDemand float64) ec2instancesinfo.RDSInstance {
+	return ec2instancesinfo.RDSInstance{
+		InstanceType:      instanceType,
+		PhysicalProcessor: processor,
+		Vcpu:              vcpu,
+		Memory:            memory,
+		Pricing: map[string]ec2instancesinfo.RDSRegionPrices{
+			"us-east-1": {PostgreSQL: ec2instancesinfo.RDSPricing{OnDemand: onDemand}},
+		},
+	}
+}
+
+func TestSuggestInstanceType(t *testing.T) {
+	rdsData := ec2instancesinfo.RDSInstanceData{
+		rdsClass("db.m5.large", "Intel Xeon", 2, 8, 0.178),
+		rdsClass("db.m5.xlarge", "Intel Xeon", 4, 16, 0.356),
+		rdsClass("db.m5.2xlarge", "Intel Xeon", 8, 32, 0.712),
+		rdsClass("db.m6g.large", "AWS Graviton2", 2, 8, 0.159),
+		rdsClass("db.m6g.xlarge", "AWS Graviton2", 4, 16, 0.318),
+		rdsClass("db.m7g.large", "AWS Graviton3", 2, 8, 0.168),
+		rdsClass("db.r6g.large", "AWS Graviton2", 2, 16, 0.225),
+	}
+
+	tests := []struct {
+		name         string
+		instanceType string
+		peakCPU      float64
+		freeMemory   float64
+		want         string
+	}{
+		{"x86 class moves to the cheapest Graviton class", "db.m5.2xlarge", 10, 28, "db.m6g.large"},
+		{"busy x86 class moves to the same size Graviton class", "db.m5.xlarge", 60, 10, "db.m6g.xlarge"},
+		{"Graviton class stays in its family", "db.m6g.xlarge", 10, 14, "db.m6g.large"},
+		{"no cheaper class fits", "db.m6g.large", 90, 1, "db.m6g.large"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, _ := suggestInstanceType(rdsData, "us-east-1", RightsizingSuggestion{
+				Engine:               "PostgreSQL",
+				InstanceType:         test.instanceType,
+				PeakCPUPercent:       test.peakCPU,
+				MinFreeableMemoryGiB: test.freeMemory,
+			})
+			if got != test.want {
+				t.Errorf("got %s, want %s", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/savingsplans"
+	savingsplanstypes "github.com/aws/aws-sdk-go-v2/service/savingsplans/types"
+	"github.com/olekukonko/tablewriter"
+)
+
+// Product descriptions of the Savings Plans rates of each EC2 platform.
+var savingsPlanProductDescriptions = map[string]string{
+	PlatformLinux:   "Linux/UNIX",
+	PlatformWindows: "Windows",
+	PlatformRHEL:    "Red Hat Enterprise Linux",
+	PlatformSUSE:    "SUSE Linux",
+}
+
+// Tenancies of the Savings Plans rates, by tenancy of the EC2 instances.
+var savingsPlanTenancies = map[string]string{
+	"shared":    TenancyDefault,
+	"dedicated": TenancyDedicated,
+}
+
+// savingsPlanRateKey identifies the Savings Plans rate of an instance type and
+// engine, its platform and tenancy, for a plan type, duration and payment option.
+type savingsPlanRateKey struct {
+	InstanceType  string
+	Engine        string
+	PlanType      savingsplanstypes.SavingsPlanType
+	DurationYears int
+	PaymentOption string
+}
+
+// Hourly Savings Plans rates of the shared and dedicated tenancy instances, cached
+// by instance type as they don't change between the groups of instances.
+var savingsPlanRates = make(map[savingsPlanRateKey]float64)
+var savingsPlanRatesFetched = make(map[string]bool)
+
+// fetchSavingsPlanRates caches the Compute and EC2 Instance Savings Plans rates of
+// an instance type in the region.
+func fetchSavingsPlanRates(svc *savingsplans.Client, region, instanceType string) error {
+	if savingsPlanRatesFetched[instanceType] {
+		return nil
+	}
+
+	platforms := make(map[string]string)
+	var productDescriptions []string
+	for platform, description := range savingsPlanProductDescriptions {
+		platforms[description] = platform
+		productDescriptions = append(productDescriptions, description)
+	}
+
+	input := &savingsplans.DescribeSavingsPlansOfferingRatesInput{
+		Products:         []savingsplanstypes.SavingsPlanProductType{savingsplanstypes.SavingsPlanProductTypeEc2},
+		ServiceCodes:     []savingsplanstypes.SavingsPlanRateServiceCode{savingsplanstypes.SavingsPlanRateServiceCodeEc2},
+		SavingsPlanTypes: []savingsplanstypes.SavingsPlanType{savingsplanstypes.SavingsPlanTypeCompute, savingsplanstypes.SavingsPlanTypeEc2Instance},
+		Filters: []savingsplanstypes.SavingsPlanOfferingRateFilterElement{
+			{Name: savingsplanstypes.SavingsPlanRateFilterAttributeRegion, Values: []string{region}},
+			{Name: savingsplanstypes.SavingsPlanRateFilterAttributeInstanceType, Values: []string{instanceType}},
+			{Name: savingsplanstypes.SavingsPlanRateFilterAttributeTenancy, Values: []string{"shared", "dedicated"}},
+			{Name: savingsplanstypes.SavingsPlanRateFilterAttributeProductDescription, Values: productDescriptions},
+		},
+	}
+	for {
+		page, err := svc.DescribeSavingsPlansOfferingRates(context.TODO(), input)
+		if err != nil {
+			errorLog.Printf("Error describing the Savings Plans rates: %v", err)
+			return err
+		}
+		for _, rate := range page.SearchResults {
+			offering := rate.SavingsPlanOffering
+			// Only the instance usage is covered, not the dedicated hosts or capacity blocks
+			if offering == nil || !strings.Contains(aws.ToString(rate.UsageType), "BoxUsage:") {
+				continue
+			}
+			properties := make(map[string]string)
+			for _, property := range rate.Properties {
+				properties[aws.ToString(property.Name)] = aws.ToString(property.Value)
+			}
+			platform, ok := platforms[properties["productDescription"]]
+			tenancy, tenancyOK := savingsPlanTenancies[properties["tenancy"]]
+			if !ok || !tenancyOK {
+				continue
+			}
+
+			hourly, err := strconv.ParseFloat(aws.ToString(rate.Rate), 64)
+			if err != nil {
+				debugLog.Printf("Skipping the Savings Plans rate %q: %v", aws.ToString(rate.Rate), err)
+				continue
+			}
+			savingsPlanRates[savingsPlanRateKey{
+				InstanceType:  instanceType,
+				Engine:        ec2Engine(platform, tenancy),
+				PlanType:      offering.PlanType,
+				DurationYears: int(offering.DurationSeconds / (hoursPerYear * 3600)),
+				PaymentOption: priceListPurchaseOptions[string(offering.PaymentOption)],
+			}] = hourly
+		}
+		if aws.ToString(page.NextToken) == "" {
+			break
+		}
+		input.NextToken = page.NextToken
+	}
+
+	savingsPlanRatesFetched[instanceType] = true
+	return nil
+}
+
+// SavingsPlanComparison compares the costs of covering the instances with
+// reservations or Savings Plans, for a term and payment option.
+type SavingsPlanComparison struct {
+	Term                  string
+	PaymentOption         string
+	OnDemandCost          float64
+	StandardCost          float64
+	ConvertibleCost       float64
+	ComputeCommitment     float64 // Hourly commitment of a Compute Savings Plan covering the instances
+	ComputeCost           float64
+	EC2InstanceCommitment float64 // Hourly commitment of EC2 Instance Savings Plans covering the instances
+	EC2InstanceCost       float64
+}
+
+// CompareSavingsPlans computes the hourly commitments of the Compute and EC2
+// Instance Savings Plans covering all the aggregated EC2 instances, and compares
+// their costs with reserving the instances, for each term and payment option.
+// Instances without a rate or reserved option are left on-demand.
+func CompareSavingsPlans(region string, instances []InstanceInfo, data1Year, data3Years []PricingData) ([]SavingsPlanComparison, error) {
+	// The Savings Plans API is global, served from us-east-1
+	cfg, err := config.LoadDefaultConfig(context.TODO(), config.WithRegion("us-east-1"))
+	if err != nil {
+		errorLog.Printf("Error loading AWS config: %v", err)
+		return nil, err
+	}
+	svc := savingsplans.NewFromConfig(cfg)
+	for _, instance := range instances {
+		if err := fetchSavingsPlanRates(svc, region, instance.InstanceType); err != nil {
+			return nil, err
+		}
+	}
+
+	discount := discountFactor(serviceName(ServiceEC2), region)
+
+	var comparisons []SavingsPlanComparison
+	for _, years := range []int{1, 3} {
+		data, term := data1Year, "1 Year"
+		if years == 3 {
+			data, term = data3Years, "3 Year"
+		}
+
+		for _, paymentOption := range []string{"noUpfront", "partialUpfront", "allUpfront"} {
+			comparison := SavingsPlanComparison{Term: term, PaymentOption: paymentOption}
+			for _, instance := range instances {
+				onDemand, ok := termCost(data, instance, "On-Demand", "", "N/A")
+				if !ok {
+					continue // Not priced, left out of the pricing tables too
+				}
+				comparison.OnDemandCost += onDemand
+
+				for _, reservation := range []struct {
+					OfferingClass string
+					Cost          *float64
+				}{
+					{"Standard", &comparison.StandardCost},
+					{"Convertible", &comparison.ConvertibleCost},
+				} {
+					cost, ok := termCost(data, instance, term, reservation.OfferingClass, paymentOption)
+					if !ok {
+						cost = onDemand
+					}
+					*reservation.Cost += cost
+				}
+
+				for _, plan := range []struct {
+					PlanType   savingsplanstypes.SavingsPlanType
+					Commitment *float64
+					Cost       *float64
+				}{
+					{savingsplanstypes.SavingsPlanTypeCompute, &comparison.ComputeCommitment, &comparison.ComputeCost},
+					{savingsplanstypes.SavingsPlanTypeEc2Instance, &comparison.EC2InstanceCommitment, &comparison.EC2InstanceCost},
+				} {
+					rate, ok := savingsPlanRates[savingsPlanRateKey{instance.InstanceType, instance.Engine, plan.PlanType, years, paymentOption}]
+					if !ok {
+						*plan.Cost += onDemand
+						continue
+					}
+					commitment := rate * discount * float64(instance.NumberOfInstances)
+					*plan.Commitment += commitment
+					*plan.Cost += commitment * termHours(years)
+				}
+			}
+			comparisons = append(comparisons, comparison)
+		}
+	}
+	return comparisons, nil
+}
+
+// termCost returns the cost over the term of an aggregated instance for one of
+// the pricing options, and whether the option is priced.
+func termCost(data []PricingData, instance InstanceInfo, term, offeringClass, paymentOption string) (float64, bool) {
+	for _, row := range data {
+		if row.InstanceType == instance.InstanceType && row.Engine == instance.Engine && row.Term == term &&
+			row.OfferingClass == offeringClass && row.PaymentOption == paymentOption && row.OfferingStatus != OfferingStatusNotOffered {
+			return row.CostForTermPerInstance * float64(instance.NumberOfInstances), true
+		}
+	}
+	return 0, false
+}
+
+// PrintSavingsPlansComparison prints the costs of the reservations and Savings
+// Plans side by side.
+func PrintSavingsPlansComparison(comparisons []SavingsPlanComparison) {
+	if len(comparisons) == 0 {
+		return
+	}
+
+	fmt.Println("\n## Savings Plans vs Reserved Instances")
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader(currencyHeaders([]string{
+		"Term",
+		"Payment Option",
+		"On-Demand Cost for Term ($)",
+		"Standard RI Cost for Term ($)",
+		"Convertible RI Cost for Term ($)",
+		"Compute SP Hourly Commitment ($)",
+		"Compute SP Cost for Term ($)",
+		"EC2 Instance SP Hourly Commitment ($)",
+		"EC2 Instance SP Cost for Term ($)",
+	}))
+	table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	table.SetCenterSeparator("|")
+
+	for _, comparison := range comparisons {
+		table.Append([]string{
+			comparison.Term,
+			comparison.PaymentOption,
+			formatCost(comparison.OnDemandCost),
+			formatCost(comparison.StandardCost),
+			formatCost(comparison.ConvertibleCost),
+			fmt.Sprintf("%.4f", comparison.ComputeCommitment*ExchangeRate),
+			formatCost(comparison.ComputeCost),
+			fmt.Sprintf("%.4f", comparison.EC2InstanceCommitment*ExchangeRate),
+			formatCost(comparison.EC2InstanceCost),
+		})
+	}
+
+	table.Render()
+}
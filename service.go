@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Services whose instances can be priced for reservations.
+const (
+	ServiceRDS         = "rds"
+	ServiceEC2         = "ec2"
+	ServiceElastiCache = "elasticache"
+	ServiceOpenSearch  = "opensearch"
+	ServiceRedshift    = "redshift"
+	ServiceMemoryDB    = "memorydb"
+)
+
+// Names of the services as used by AWS, such as in the discounts configuration.
+var serviceNames = map[string]string{
+	ServiceRDS:         "RDS",
+	ServiceEC2:         "EC2",
+	ServiceElastiCache: "ElastiCache",
+	ServiceOpenSearch:  "OpenSearch",
+	ServiceRedshift:    "Redshift",
+	ServiceMemoryDB:    "MemoryDB",
+}
+
+// ParseService validates the service name.
+func ParseService(service string) (string, error) {
+	service = strings.ToLower(service)
+	if _, ok := serviceNames[service]; !ok {
+		services := make([]string, 0, len(serviceNames))
+		for name := range serviceNames {
+			services = append(services, name)
+		}
+		sort.Strings(services)
+		return "", fmt.Errorf("unknown service %q, expected one of %s", service, strings.Join(services, ", "))
+	}
+	return service, nil
+}
+
+// serviceName returns the AWS name of a service.
+func serviceName(service string) string {
+	return serviceNames[service]
+}
+
+// serviceColumns adapts the pricing table columns to the instances of the service.
+func serviceColumns(service string, columns []string) []string {
+	switch service {
+	case ServiceEC2:
+		return ec2Columns(columns)
+	case ServiceElastiCache, ServiceRedshift, ServiceMemoryDB:
+		return renameColumn(columns, "Databases", "Clusters")
+	case ServiceOpenSearch:
+		return renameColumn(columns, "Databases", "Domains")
+	}
+	return columns
+}
+
+// renameColumn replaces a column of the pricing tables by another one.
+func renameColumn(columns []string, column, replacement string) []string {
+	renamed := make([]string, len(columns))
+	for i, c := range columns {
+		if c == column {
+			c = replacement
+		}
+		renamed[i] = c
+	}
+	return renamed
+}
+
+// checkServiceFlags returns an error when an option only implemented for some of
+// the services, mostly RDS, is used with another service.
+func checkServiceFlags(service string) error {
+	if CompareSavingsPlansFlag && service != ServiceEC2 {
+		return fmt.Errorf("-savings-plans is only supported for %s", ServiceEC2)
+	}
+	if len(CommitmentDiscounts) > 0 && !isCommitmentService(service) {
+		return fmt.Errorf("-commitment-discounts isn't supported for %s, only for the database services", service)
+	}
+	if service == ServiceRDS {
+		return nil
+	}
+
+	rdsOnly := []struct {
+		Flag string
+		Set  bool
+	}{
+		{"-terraform", TerraformFile != ""},
+		{"-cloudformation", CloudFormationFile != ""},
+		{"-uptime-days", UptimeDays > 0},
+		{"-rightsizing-days", RightsizingDays > 0},
+		{"-graviton", CompareGravitonFlag},
+		{"-forecast", ForecastFile != ""},
+		{"-coverage-days", CoverageDays > 0},
+		{"-validate-offerings", ValidateOfferings},
+		{"-plan-out", PlanOutFile != ""},
+	}
+	for _, option := range rdsOnly {
+		if option.Set {
+			return fmt.Errorf("%s is only supported for %s", option.Flag, ServiceRDS)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// Status policies controlling which DB instance statuses are considered part of
+// the long running fleet and priced for reservations.
+const (
+	StatusPolicyAvailable = "available" // Only instances in the available state
+	StatusPolicyActive    = "active"    // Instances that are up or only temporarily busy
+	StatusPolicyAll       = "all"       // Also include stopped instances
+)
+
+var statusPolicies = map[string][]string{
+	StatusPolicyAvailable: {
+		"available",
+	},
+	StatusPolicyActive: {
+		"available",
+		"backing-up",
+		"configuring-enhanced-monitoring",
+		"configuring-iam-database-auth",
+		"configuring-log-exports",
+		"converting-to-vpc",
+		"creating",
+		"maintenance",
+		"modifying",
+		"moving-to-vpc",
+		"rebooting",
+		"renaming",
+		"resetting-master-credentials",
+		"starting",
+		"storage-config-upgrade",
+		"storage-full",
+		"storage-optimization",
+		"upgrading",
+	},
+	StatusPolicyAll: {
+		"available",
+		"backing-up",
+		"configuring-enhanced-monitoring",
+		"configuring-iam-database-auth",
+		"configuring-log-exports",
+		"converting-to-vpc",
+		"creating",
+		"maintenance",
+		"modifying",
+		"moving-to-vpc",
+		"rebooting",
+		"renaming",
+		"resetting-master-credentials",
+		"starting",
+		"stopped",
+		"stopping",
+		"storage-config-upgrade",
+		"storage-full",
+		"storage-optimization",
+		"upgrading",
+	},
+}
+
+// EC2 instance states included by each status policy.
+var ec2StatusPolicies = map[string][]string{
+	StatusPolicyAvailable: {"running"},
+	StatusPolicyActive:    {"pending", "running"},
+	StatusPolicyAll:       {"pending", "running", "stopping", "stopped"},
+}
+
+// ElastiCache cluster statuses included by each status policy, clusters can't be stopped.
+var elastiCacheStatusPolicies = map[string][]string{
+	StatusPolicyAvailable: {"available"},
+	StatusPolicyActive:    {"available", "creating", "modifying", "rebooting cluster nodes", "snapshotting"},
+	StatusPolicyAll:       {"available", "creating", "modifying", "rebooting cluster nodes", "snapshotting"},
+}
+
+// OpenSearch domain processing statuses included by each status policy, domains
+// can't be stopped.
+var openSearchStatusPolicies = map[string][]string{
+	StatusPolicyAvailable: {"Active"},
+	StatusPolicyActive:    {"Active", "Creating", "Modifying", "UpgradingEngineVersion", "UpdatingServiceSoftware"},
+	StatusPolicyAll:       {"Active", "Creating", "Modifying", "UpgradingEngineVersion", "UpdatingServiceSoftware"},
+}
+
+// Redshift cluster statuses included by each status policy, paused clusters only
+// being billed for their storage.
+var redshiftStatusPolicies = map[string][]string{
+	StatusPolicyAvailable: {"available"},
+	StatusPolicyActive:    {"available", "creating", "modifying", "rebooting", "renaming", "resizing"},
+	StatusPolicyAll:       {"available", "creating", "modifying", "paused", "rebooting", "renaming", "resizing"},
+}
+
+// MemoryDB cluster statuses included by each status policy, clusters can't be stopped.
+var memoryDBStatusPolicies = map[string][]string{
+	StatusPolicyAvailable: {"available"},
+	StatusPolicyActive:    {"available", "creating", "updating", "snapshotting"},
+	StatusPolicyAll:       {"available", "creating", "updating", "snapshotting"},
+}
+
+// ExcludedInstance is a DB instance left out of the pricing tables.
+type ExcludedInstance struct {
+	Identifier   string
+	InstanceType string
+	Engine       string
+	Status       string
+	Reason       string
+}
+
+// ParseStatusPolicy validates the status policy name and returns the statuses it
+// includes for the instances of the service.
+func ParseStatusPolicy(policy, service string) ([]string, error) {
+	policies := statusPolicies
+	switch service {
+	case ServiceEC2:
+		policies = ec2StatusPolicies
+	case ServiceElastiCache:
+		policies = elastiCacheStatusPolicies
+	case ServiceOpenSearch:
+		policies = openSearchStatusPolicies
+	case ServiceRedshift:
+		policies = redshiftStatusPolicies
+	case ServiceMemoryDB:
+		policies = memoryDBStatusPolicies
+	}
+	statuses, ok := policies[strings.ToLower(policy)]
+	if !ok {
+		return nil, fmt.Errorf("unknown status policy %q, expected one of %s, %s or %s",
+			policy, StatusPolicyAvailable, StatusPolicyActive, StatusPolicyAll)
+	}
+	return statuses, nil
+}
+
+// isStatusIncluded reports whether the status is part of the given list of statuses.
+func isStatusIncluded(status string, statuses []string) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// PrintExcludedInstances prints the instances that were left out of the pricing tables and why.
+func PrintExcludedInstances(excluded []ExcludedInstance) {
+	if len(excluded) == 0 {
+		return
+	}
+
+	fmt.Println("\n## Excluded Instances")
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Identifier", "Instance Type", "Engine", "Status", "Reason"})
+	table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	table.SetCenterSeparator("|")
+
+	for _, instance := range excluded {
+		table.Append([]string{instance.Identifier, instance.InstanceType, instance.Engine, instance.Status, instance.Reason})
+	}
+
+	table.Render()
+}
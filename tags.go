@@ -0,0 +1,197 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rds/types"
+	"github.com/olekukonko/tablewriter"
+)
+
+// UntaggedGroup is the group of the instances missing the tag used for grouping.
+const UntaggedGroup = "untagged"
+
+// InstanceGroup is a set of instances sharing the same value of the grouping tag.
+type InstanceGroup struct {
+	Name      string
+	Instances []InstanceInfo
+}
+
+// SavingsSummary holds the savings of a group of instances for a term, using
+// the cheapest reserved option available for each instance type.
+type SavingsSummary struct {
+	Group             string
+	Term              string
+	OnDemandCost      float64
+	BestReservedCost  float64
+	Savings           float64
+	SavingsPercent    float64
+	NumberOfInstances int
+}
+
+func tagsFromRDSTagList(tagList []types.Tag) map[string]string {
+	tags := make(map[string]string, len(tagList))
+	for _, tag := range tagList {
+		tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	return tags
+}
+
+// FilterInstancesByTags keeps the instances having all the given tag values.
+func FilterInstancesByTags(instances []InstanceInfo, filters map[string]string) []InstanceInfo {
+	if len(filters) == 0 {
+		return instances
+	}
+
+	var filtered []InstanceInfo
+	for _, instance := range instances {
+		matches := true
+		for key, value := range filters {
+			if tagValue, ok := instance.Tags[key]; !ok || tagValue != value {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			filtered = append(filtered, instance)
+		}
+	}
+	debugLog.Printf("Instances matching the tag filters %v: %v", filters, filtered)
+	return filtered
+}
+
+// GroupInstancesByTag groups the instances by the value of the given tag, sorted by
+// value with the untagged instances last. Without a tag all instances form one group.
+func GroupInstancesByTag(instances []InstanceInfo, tag string) []InstanceGroup {
+	if tag == "" {
+		return []InstanceGroup{{Instances: instances}}
+	}
+
+	grouped := make(map[string][]InstanceInfo)
+	for _, instance := range instances {
+		value, ok := instance.Tags[tag]
+		if !ok || value == "" {
+			value = UntaggedGroup
+		}
+		grouped[value] = append(grouped[value], instance)
+	}
+
+	var names []string
+	for name := range grouped {
+		if name != UntaggedGroup {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	if _, ok := grouped[UntaggedGroup]; ok {
+		names = append(names, UntaggedGroup)
+	}
+
+	var groups []InstanceGroup
+	for _, name := range names {
+		groups = append(groups, InstanceGroup{Name: name, Instances: grouped[name]})
+	}
+	return groups
+}
+
+// InstanceTypeCosts holds the term costs of all the instances of a type and engine.
+type InstanceTypeCosts struct {
+	InstanceType      string
+	Engine            string
+	NumberOfInstances int
+	OnDemandCost      float64
+	BestReservedCost  float64 // Cheapest reserved option, or the on-demand cost if none is cheaper
+}
+
+// CostsByInstanceType computes the on-demand and cheapest reserved costs for the
+// term of each instance type and engine, keyed by type and engine.
+func CostsByInstanceType(data []PricingData, instances []InstanceInfo, term string) map[string]InstanceTypeCosts {
+	enginesInUse := make(map[string]bool)
+	for _, instance := range instances {
+		enginesInUse[instance.Engine] = true
+	}
+
+	costs := make(map[string]InstanceTypeCosts)
+	reservedCosts := make(map[string]float64)
+	for engine := range enginesInUse {
+		for _, row := range AggregateCostsByTermAndEngine(data, instances, term, engine) {
+			key := fmt.Sprintf("%s-%s", row.InstanceType, row.Engine)
+			if row.Term == "On-Demand" {
+				costs[key] = InstanceTypeCosts{
+					InstanceType:      row.InstanceType,
+					Engine:            row.Engine,
+					NumberOfInstances: row.NumberOfInstances,
+					OnDemandCost:      row.TotalCostForTerm,
+				}
+				continue
+			}
+			if row.OfferingStatus == OfferingStatusNotOffered {
+				continue
+			}
+			if best, ok := reservedCosts[key]; !ok || row.TotalCostForTerm < best {
+				reservedCosts[key] = row.TotalCostForTerm
+			}
+		}
+	}
+
+	for key, cost := range costs {
+		cost.BestReservedCost = cost.OnDemandCost // Nothing to reserve, keep paying on-demand
+		if reservedCost, ok := reservedCosts[key]; ok {
+			cost.BestReservedCost = math.Min(reservedCost, cost.OnDemandCost)
+		}
+		costs[key] = cost
+	}
+	return costs
+}
+
+// SummarizeSavings computes the on-demand and cheapest reserved costs for the term
+// across all the instance types and engines of a group.
+func SummarizeSavings(group string, data []PricingData, instances []InstanceInfo, term string) SavingsSummary {
+	summary := SavingsSummary{Group: group, Term: term}
+
+	for _, cost := range CostsByInstanceType(data, instances, term) {
+		summary.OnDemandCost += cost.OnDemandCost
+		summary.BestReservedCost += cost.BestReservedCost
+		summary.NumberOfInstances += cost.NumberOfInstances
+	}
+
+	summary.Savings = summary.OnDemandCost - summary.BestReservedCost
+	if summary.OnDemandCost != 0 {
+		summary.SavingsPercent = summary.Savings / summary.OnDemandCost * 100
+	}
+	return summary
+}
+
+// PrintSavingsSummary prints the savings totals of each group.
+func PrintSavingsSummary(summaries []SavingsSummary, tag string) {
+	fmt.Printf("\n## Savings by %s\n", tag)
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader(currencyHeaders([]string{
+		tag,
+		"Term",
+		"Number of Instances",
+		"On-Demand Cost for Term ($)",
+		"Best Reserved Cost for Term ($)",
+		"Savings ($)",
+		"Savings (%)",
+	}))
+	table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	table.SetCenterSeparator("|")
+
+	for _, summary := range summaries {
+		table.Append([]string{
+			summary.Group,
+			summary.Term,
+			fmt.Sprintf("%d", summary.NumberOfInstances),
+			formatCost(summary.OnDemandCost),
+			formatCost(summary.BestReservedCost),
+			formatCost(summary.Savings),
+			fmt.Sprintf("%.2f", summary.SavingsPercent),
+		})
+	}
+
+	table.Render()
+}
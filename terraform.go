@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// terraformShow is the subset of the 'terraform show -json' output we need. State
+// files carry their resources under "values", plan files under "planned_values".
+type terraformShow struct {
+	Values        *terraformValues `json:"values"`
+	PlannedValues *terraformValues `json:"planned_values"`
+}
+
+type terraformValues struct {
+	RootModule terraformModule `json:"root_module"`
+}
+
+type terraformModule struct {
+	Resources    []terraformResource `json:"resources"`
+	ChildModules []terraformModule   `json:"child_modules"`
+}
+
+type terraformResource struct {
+	Address string                `json:"address"`
+	Mode    string                `json:"mode"`
+	Type    string                `json:"type"`
+	Values  terraformResourceAttr `json:"values"`
+}
+
+type terraformResourceAttr struct {
+	InstanceClass string            `json:"instance_class"`
+	Engine        string            `json:"engine"`
+	LicenseModel  string            `json:"license_model"`
+	MultiAZ       bool              `json:"multi_az"`
+	Identifier    string            `json:"identifier"`
+	ARN           string            `json:"arn"`
+	EngineVersion string            `json:"engine_version"`
+	AZ            string            `json:"availability_zone"`
+	Tags          map[string]string `json:"tags"`
+	TagsAll       map[string]string `json:"tags_all"`
+}
+
+// GetTerraformInstances reads the RDS instances declared in a 'terraform show -json'
+// state or plan file. Resources using count or for_each are expanded by Terraform,
+// so every entry found in the file is a single database instance.
+func GetTerraformInstances(path string) ([]InstanceInfo, error) {
+	body, err := os.ReadFile(path)
+	if err != nil {
+		errorLog.Printf("Error reading Terraform file: %v", err)
+		return nil, err
+	}
+
+	var show terraformShow
+	if err := json.Unmarshal(body, &show); err != nil {
+		errorLog.Printf("Error parsing Terraform file: %v", err)
+		return nil, err
+	}
+
+	values := show.PlannedValues
+	if values == nil {
+		values = show.Values
+	}
+	if values == nil {
+		return nil, fmt.Errorf("no state or planned values found in %s, was it generated with 'terraform show -json'?", path)
+	}
+
+	instances := parseTerraformModule(values.RootModule)
+
+	debugLog.Printf("Found Terraform instances: %v", instances)
+	return instances, nil
+}
+
+func parseTerraformModule(module terraformModule) []InstanceInfo {
+	var instances []InstanceInfo
+	for _, resource := range module.Resources {
+		if resource.Mode != "managed" {
+			continue // Skip data sources
+		}
+
+		switch resource.Type {
+		case "aws_db_instance", "aws_rds_cluster_instance", "aws_docdb_cluster_instance", "aws_neptune_cluster_instance":
+			if resource.Values.InstanceClass == "" {
+				debugLog.Printf("Skipping %s, its instance class is not known yet", resource.Address)
+				continue
+			}
+			instances = append(instances, InstanceInfo{
+				InstanceType:      resource.Values.InstanceClass,
+				NumberOfInstances: instanceCountForDeployment(1, resource.Values.MultiAZ),
+				Engine:            rdsEngine(resource.Values.Engine, resource.Values.LicenseModel),
+				MultiAZ:           resource.Values.MultiAZ,
+				Tags:              terraformTags(resource.Values),
+				Identifier:        terraformIdentifier(resource),
+				ARN:               resource.Values.ARN,
+				EngineVersion:     resource.Values.EngineVersion,
+				AvailabilityZone:  resource.Values.AZ,
+			})
+		}
+	}
+
+	for _, child := range module.ChildModules {
+		instances = append(instances, parseTerraformModule(child)...)
+	}
+	return instances
+}
+
+// terraformTags merges the resource tags with the provider default tags found in tags_all.
+func terraformTags(values terraformResourceAttr) map[string]string {
+	tags := make(map[string]string, len(values.TagsAll))
+	for key, value := range values.TagsAll {
+		tags[key] = value
+	}
+	for key, value := range values.Tags {
+		tags[key] = value
+	}
+	return tags
+}
+
+// terraformIdentifier returns the DB identifier when it is known, the resource address otherwise.
+func terraformIdentifier(resource terraformResource) string {
+	if resource.Values.Identifier != "" {
+		return resource.Values.Identifier
+	}
+	return resource.Address
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+)
+
+// Maximum number of queries accepted by a single GetMetricData call.
+const maxMetricDataQueries = 500
+
+// rdsMetricQuery identifies an hourly statistic of an RDS instance metric.
+type rdsMetricQuery struct {
+	Identifier string
+	MetricName string
+	Stat       string
+}
+
+// getRDSMetricValues fetches the hourly datapoints of the given RDS instance metrics
+// between start and end. Hours without datapoints are missing from the results.
+func getRDSMetricValues(region string, metrics []rdsMetricQuery, start, end time.Time) (map[rdsMetricQuery][]float64, error) {
+	values := make(map[rdsMetricQuery][]float64)
+	err := fetchRDSMetricData(region, metrics, start, end, func(metric rdsMetricQuery, result types.MetricDataResult) {
+		values[metric] = append(values[metric], result.Values...)
+	})
+	return values, err
+}
+
+// getRDSMetricHours fetches the hours between start and end having datapoints for
+// the given RDS instance metrics.
+func getRDSMetricHours(region string, metrics []rdsMetricQuery, start, end time.Time) (map[rdsMetricQuery][]time.Time, error) {
+	hours := make(map[rdsMetricQuery][]time.Time)
+	err := fetchRDSMetricData(region, metrics, start, end, func(metric rdsMetricQuery, result types.MetricDataResult) {
+		hours[metric] = append(hours[metric], result.Timestamps...)
+	})
+	return hours, err
+}
+
+// fetchRDSMetricData runs the GetMetricData queries of the given RDS instance metrics
+// in batches, passing each page of results to the handler.
+func fetchRDSMetricData(region string, metrics []rdsMetricQuery, start, end time.Time, handle func(rdsMetricQuery, types.MetricDataResult)) error {
+	cfg, err := config.LoadDefaultConfig(context.TODO(), config.WithRegion(region))
+	if err != nil {
+		errorLog.Printf("Error loading AWS config: %v", err)
+		return err
+	}
+	svc := cloudwatch.NewFromConfig(cfg)
+
+	var queries []types.MetricDataQuery
+	queryMetrics := make(map[string]rdsMetricQuery)
+	for i, metric := range metrics {
+		id := fmt.Sprintf("m%d", i)
+		queryMetrics[id] = metric
+		queries = append(queries, types.MetricDataQuery{
+			Id: aws.String(id),
+			MetricStat: &types.MetricStat{
+				Metric: &types.Metric{
+					Namespace:  aws.String("AWS/RDS"),
+					MetricName: aws.String(metric.MetricName),
+					Dimensions: []types.Dimension{
+						{Name: aws.String("DBInstanceIdentifier"), Value: aws.String(metric.Identifier)},
+					},
+				},
+				Period: aws.Int32(3600),
+				Stat:   aws.String(metric.Stat),
+			},
+		})
+	}
+
+	for first := 0; first < len(queries); first += maxMetricDataQueries {
+		batch := queries[first:min(first+maxMetricDataQueries, len(queries))]
+		paginator := cloudwatch.NewGetMetricDataPaginator(svc, &cloudwatch.GetMetricDataInput{
+			StartTime:         aws.Time(start),
+			EndTime:           aws.Time(end),
+			MetricDataQueries: batch,
+		})
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(context.TODO())
+			if err != nil {
+				errorLog.Printf("Error fetching CloudWatch metrics: %v", err)
+				return err
+			}
+			for _, result := range page.MetricDataResults {
+				handle(queryMetrics[aws.ToString(result.Id)], result)
+			}
+		}
+	}
+	return nil
+}
+
+// GetInstanceUptimeHours returns, for each instance identifier, the number of hours
+// of the last days having CPUUtilization datapoints, in other words the hours the
+// instance has been running.
+func GetInstanceUptimeHours(region string, instances []InstanceInfo, days int, end time.Time) (map[string]int, error) {
+	var metrics []rdsMetricQuery
+	for _, instance := range instances {
+		metrics = append(metrics, rdsMetricQuery{Identifier: instance.Identifier, MetricName: "CPUUtilization", Stat: "Maximum"})
+	}
+
+	values, err := getRDSMetricValues(region, metrics, end.AddDate(0, 0, -days), end)
+	if err != nil {
+		return nil, err
+	}
+
+	uptimeHours := make(map[string]int)
+	for metric, datapoints := range values {
+		uptimeHours[metric.Identifier] = len(datapoints)
+	}
+
+	debugLog.Printf("Instance uptime hours over the last %d days: %v", days, uptimeHours)
+	return uptimeHours, nil
+}
+
+// FilterInstancesByUptime keeps the instances that have been running continuously
+// over the last days. Instances not created yet or recently, and the ones stopped
+// for more than the allowed share of the time are returned as excluded instances.
+func FilterInstancesByUptime(region string, instances []InstanceInfo, days int, minUptimePercent float64) ([]InstanceInfo, []ExcludedInstance, error) {
+	end := time.Now().Truncate(time.Hour)
+	windowStart := end.AddDate(0, 0, -days)
+
+	uptimeHours, err := GetInstanceUptimeHours(region, instances, days, end)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hoursInWindow := end.Sub(windowStart).Hours()
+
+	var kept []InstanceInfo
+	var excluded []ExcludedInstance
+	for _, instance := range instances {
+		reason := ""
+		uptimePercent := float64(uptimeHours[instance.Identifier]) / hoursInWindow * 100
+
+		switch {
+		case instance.CreationTime.IsZero():
+			// Instances still being created, such as the creating ones, have no creation time yet
+			reason = fmt.Sprintf("Too new to judge: not created yet, no %d days history", days)
+		case instance.CreationTime.After(windowStart):
+			reason = fmt.Sprintf("Short-lived: created %d days ago, less than the %d days history required",
+				int(end.Sub(instance.CreationTime).Hours()/24), days)
+		case uptimePercent < minUptimePercent:
+			reason = fmt.Sprintf("Intermittent: running %.1f%% of the last %d days, below %.1f%%",
+				uptimePercent, days, minUptimePercent)
+		}
+
+		if reason == "" {
+			kept = append(kept, instance)
+			continue
+		}
+		excluded = append(excluded, ExcludedInstance{
+			Identifier:   instance.Identifier,
+			InstanceType: instance.InstanceType,
+			Engine:       instance.Engine,
+			Status:       instance.Status,
+			Reason:       reason,
+		})
+	}
+	return kept, excluded, nil
+}